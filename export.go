@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/archive"
+	"github.com/BYT0723/bilichat/internal/config"
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/BYT0723/bilichat/internal/export"
+)
+
+// runExport 导出一场直播的聊天记录, 字幕格式的时间轴以开播时间为零点
+func runExport(args []string) {
+	var (
+		fs       = flag.NewFlagSet("export", flag.ExitOnError)
+		room     int64
+		session  string
+		list     bool
+		format   string
+		output   string
+		types    string
+		start    string
+		duration time.Duration
+		ass      = export.DefaultASSOptions
+	)
+	fs.Int64Var(&room, "room", config.Config.RoomID, "room id")
+	fs.StringVar(&session, "session", "", "session name, defaults to the latest session of the room")
+	fs.BoolVar(&list, "list", false, "list archived sessions and exit")
+	fs.StringVar(&format, "format", "", "output format: "+strings.Join(export.Formats, ", ")+", inferred from -o by default")
+	fs.StringVar(&output, "o", "", "output file, defaults to stdout")
+	fs.StringVar(&types, "type", "", "comma separated event types to export, defaults to all")
+	fs.StringVar(&start, "start", "", "override stream start time, e.g. \"2006-01-02 15:04:05\"")
+	fs.DurationVar(&duration, "duration", 5*time.Second, "display duration of each srt line")
+	fs.IntVar(&ass.Width, "width", ass.Width, "ass video width")
+	fs.IntVar(&ass.Height, "height", ass.Height, "ass video height")
+	fs.StringVar(&ass.FontName, "font", ass.FontName, "ass font name")
+	fs.IntVar(&ass.FontSize, "fontsize", ass.FontSize, "ass font size")
+	fs.DurationVar(&ass.Duration, "scroll", ass.Duration, "time for an ass danmaku to scroll across the screen")
+	fs.Float64Var(&ass.Area, "area", ass.Area, "fraction of the screen height used by ass danmaku")
+	_ = fs.Parse(args)
+
+	sessions, err := archive.Sessions(config.ArchiveDir())
+	if err != nil {
+		exitf("list sessions: %v", err)
+	}
+	if list {
+		for _, s := range sessions {
+			if room == 0 || s.Room == room {
+				fmt.Printf("%d\t%s\t%s\n", s.Room, s.Name, s.ModTime.Format(time.DateTime))
+			}
+		}
+		return
+	}
+
+	sessions = slices.DeleteFunc(sessions, func(s archive.Session) bool {
+		return s.Room != room || (session != "" && s.Name != session)
+	})
+	if len(sessions) == 0 {
+		if config.Config.Archive.Disable {
+			exitf("no archived session of room %d %s, %s", room, session, archiveDisabled)
+		}
+		exitf("no archived session of room %d %s", room, session)
+	}
+	// 按开始时间排序, 最后一个即为最近的场次
+	s := sessions[len(sessions)-1]
+
+	var filter []event.Type
+	if types != "" {
+		if filter, err = archive.ParseTypes(types); err != nil {
+			exitf("invalid -type: %v", err)
+		}
+	}
+	var events []event.Event
+	if err := archive.ReadFile(s.Path, func(e event.Event) error {
+		if len(filter) == 0 || slices.Contains(filter, e.Type) {
+			events = append(events, e)
+		}
+		return nil
+	}); err != nil {
+		exitf("read %s: %v", s.Path, err)
+	}
+
+	// 开播时间优先使用参数, 其次是场次记录的开播时间, 离线场次以第一条事件为零点
+	startAt := s.LiveTime()
+	if start != "" {
+		if startAt, err = archive.ParseTime(start, time.Now()); err != nil {
+			exitf("invalid -start: %v", err)
+		}
+	} else if startAt.IsZero() && len(events) > 0 {
+		startAt = events[0].Timestamp
+	}
+
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(output), ".")
+	}
+	if format == "" {
+		format = "csv"
+	}
+	if format == "ass" {
+		if err := ass.Validate(); err != nil {
+			exitf("%v", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			exitf("create %s: %v", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch format {
+	case "csv":
+		err = export.CSV(w, events, startAt)
+	case "json":
+		err = export.JSON(w, events)
+	case "srt":
+		err = export.SRT(w, events, startAt, duration)
+	case "ass":
+		err = export.ASS(w, events, startAt, ass)
+	default:
+		exitf("unknown format %q, supported: %s", format, strings.Join(export.Formats, ", "))
+	}
+	if err != nil {
+		exitf("export: %v", err)
+	}
+}
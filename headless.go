@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/BYT0723/go-tools/logx"
+)
+
+// runHeadless 不启动界面, 将所有直播间的消息逐行以 JSON 写入 w,
+// 所有客户端的消息通道关闭或收到中断信号时返回
+func runHeadless(w io.Writer, rooms []client.Room) {
+	var (
+		mu   sync.Mutex
+		enc  = json.NewEncoder(w)
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	enc.SetEscapeHTML(false)
+
+	for _, r := range rooms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range r.Client.Receive() {
+				e, ok := event.FromMessage(r.ID, msg)
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				err := enc.Encode(e)
+				mu.Unlock()
+				if err != nil {
+					logx.Errorf("write event, err: %v", err)
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case <-done:
+	case <-sig:
+	}
+}
@@ -0,0 +1,294 @@
+// Package archive 将直播间的聊天事件按直播间与直播场次追加写入 JSON Lines 文件,
+// 目录结构为 <dir>/<房间号>/<场次>.jsonl, 每行为一个 event.Event.
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/BYT0723/go-tools/logx"
+)
+
+const (
+	// Ext 记录文件扩展名
+	Ext = ".jsonl"
+	// sessionLayout 场次名称的时间格式
+	sessionLayout = "20060102-150405"
+	// offlinePrefix 未开播时的场次名称前缀, 后接程序启动时间
+	offlinePrefix = "offline-"
+	// maxPending 获取到房间信息前暂存的事件数
+	maxPending = 4096
+	// bufferSize 每个记录文件的写缓冲, 写满时落盘
+	bufferSize = 64 << 10
+	// flushInterval 定时落盘的间隔, 异常退出时最多丢失这段时间内的记录
+	flushInterval = time.Second
+	// pruneInterval 删除过期场次的间隔, 长时间运行时也能按保留时间清理
+	pruneInterval = time.Hour
+)
+
+// archived 需要保存的事件类型
+var archived = map[event.Type]bool{
+	event.TypeDanmaku:   true,
+	event.TypeSuperChat: true,
+	event.TypeGift:      true,
+	event.TypeGuard:     true,
+	event.TypeEnter:     true,
+	event.TypeFollow:    true,
+	event.TypeShare:     true,
+}
+
+// ErrClosed 记录已关闭
+var ErrClosed = errors.New("archive closed")
+
+// room 单个直播间当前写入的场次
+type room struct {
+	session string
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	pending []event.Event // 场次未知时暂存的事件
+}
+
+type Archive struct {
+	dir       string
+	started   time.Time
+	retention time.Duration
+
+	mu     sync.Mutex
+	rooms  map[int64]*room
+	closed bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open 打开 dir 下的记录, retention 大于 0 时删除超过保留时间的场次
+func Open(dir string, retention time.Duration) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	a := &Archive{
+		dir:       dir,
+		started:   time.Now(),
+		retention: retention,
+		rooms:     make(map[int64]*room),
+		done:      make(chan struct{}),
+	}
+	a.prune()
+	a.wg.Add(1)
+	go a.flushLoop()
+	return a, nil
+}
+
+// prune 删除超过保留时间的场次, 正在写入的场次除外
+func (a *Archive) prune() {
+	if a.retention <= 0 {
+		return
+	}
+	a.mu.Lock()
+	open := make(map[string]bool, len(a.rooms))
+	for roomID, r := range a.rooms {
+		if r.file != nil {
+			open[SessionPath(a.dir, roomID, r.session)] = true
+		}
+	}
+	a.mu.Unlock()
+
+	if err := prune(a.dir, time.Now().Add(-a.retention), open); err != nil {
+		logx.Errorf("prune archive, err: %v", err)
+	}
+}
+
+// flushLoop 定时将缓冲的记录落盘, 并定时删除过期的场次
+func (a *Archive) flushLoop() {
+	defer a.wg.Done()
+	var (
+		ticker      = time.NewTicker(flushInterval)
+		pruneTicker = time.NewTicker(pruneInterval)
+	)
+	defer ticker.Stop()
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-pruneTicker.C:
+			a.prune()
+		case <-ticker.C:
+			a.mu.Lock()
+			for roomID, r := range a.rooms {
+				if r.w == nil || r.w.Buffered() == 0 {
+					continue
+				}
+				if err := r.w.Flush(); err != nil {
+					logx.Errorf("flush archive of room %d, err: %v", roomID, err)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// SessionName 场次名称, 未开播时使用 started 作为离线场次
+func SessionName(liveTime, started time.Time) string {
+	if liveTime.IsZero() {
+		return offlinePrefix + started.Format(sessionLayout)
+	}
+	return liveTime.Format(sessionLayout)
+}
+
+// Record 保存一条客户端消息, 房间信息用于确定当前场次, 其余不需要保存的消息被忽略
+func (a *Archive) Record(roomID int64, msg client.Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+
+	r, ok := a.rooms[roomID]
+	if !ok {
+		r = new(room)
+		a.rooms[roomID] = r
+	}
+
+	if info, ok := msg.Data.(*bilibili.RoomInfo); ok {
+		if err := a.rotate(roomID, r, SessionName(info.LiveTime, a.started)); err != nil {
+			logx.Errorf("open archive of room %d, err: %v", roomID, err)
+		}
+		return
+	}
+
+	e, ok := event.FromMessage(roomID, msg)
+	if !ok || !archived[e.Type] {
+		return
+	}
+	if r.enc == nil {
+		if len(r.pending) < maxPending {
+			r.pending = append(r.pending, e)
+		}
+		return
+	}
+	a.write(roomID, r, e)
+}
+
+// rotate 切换到指定场次的文件并写入暂存的事件
+func (a *Archive) rotate(roomID int64, r *room, session string) error {
+	if r.enc != nil && r.session == session {
+		return nil
+	}
+	if r.file != nil {
+		r.w.Flush()
+		r.file.Close()
+		r.file, r.w, r.enc = nil, nil, nil
+	}
+
+	path := SessionPath(a.dir, roomID, session)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	r.session = session
+	r.file = f
+	r.w = bufio.NewWriterSize(f, bufferSize)
+	r.enc = json.NewEncoder(r.w)
+	r.enc.SetEscapeHTML(false)
+
+	for _, e := range r.pending {
+		a.write(roomID, r, e)
+	}
+	r.pending = nil
+	return nil
+}
+
+func (a *Archive) write(roomID int64, r *room, e event.Event) {
+	// 写入缓冲, 由 flushLoop 定时或缓冲写满时落盘
+	if err := r.enc.Encode(e); err != nil {
+		logx.Errorf("write archive of room %d, err: %v", roomID, err)
+	}
+}
+
+// Close 落盘并关闭所有记录文件, 未确定场次的暂存事件写入离线场次
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	close(a.done)
+	a.mu.Unlock()
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var errs []error
+	for roomID, r := range a.rooms {
+		if r.enc == nil && len(r.pending) > 0 {
+			if err := a.rotate(roomID, r, SessionName(time.Time{}, a.started)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if r.file != nil {
+			errs = append(errs, r.w.Flush(), r.file.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SessionPath 指定直播间场次的记录文件路径
+func SessionPath(dir string, roomID int64, session string) string {
+	return filepath.Join(dir, strconv.FormatInt(roomID, 10), session+Ext)
+}
+
+// prune 删除最后修改时间早于 before 的场次, 以及删除后为空的直播间目录, keep 中的文件保留
+func prune(dir string, before time.Time, keep map[string]bool) error {
+	rooms, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, rd := range rooms {
+		if !rd.IsDir() {
+			continue
+		}
+		roomDir := filepath.Join(dir, rd.Name())
+		files, err := os.ReadDir(roomDir)
+		if err != nil {
+			return err
+		}
+		remain := len(files)
+		for _, f := range files {
+			path := filepath.Join(roomDir, f.Name())
+			if f.IsDir() || filepath.Ext(f.Name()) != Ext || keep[path] {
+				continue
+			}
+			fi, err := f.Info()
+			if err != nil {
+				return err
+			}
+			if fi.ModTime().Before(before) {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				remain--
+			}
+		}
+		if remain == 0 {
+			_ = os.Remove(roomDir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+)
+
+// writeSession 创建一个最后修改时间为 mtime 的场次文件
+func writeSession(t *testing.T, dir string, roomID int64, session string, mtime time.Time) string {
+	t.Helper()
+	path := SessionPath(dir, roomID, session)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestPrune(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		old     = time.Now().Add(-48 * time.Hour)
+		expired = writeSession(t, dir, 1, "20240101-000000", old)
+		recent  = writeSession(t, dir, 1, "20240102-000000", time.Now())
+		empty   = writeSession(t, dir, 2, "20240101-000000", old)
+	)
+
+	// 打开时删除过期的场次与删除后为空的直播间目录
+	a, err := Open(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if exists(expired) || exists(filepath.Dir(empty)) {
+		t.Error("expired sessions are not pruned on Open")
+	}
+	if !exists(recent) {
+		t.Error("recent session is pruned")
+	}
+
+	// 运行期间再次清理, 正在写入的场次即使过期也保留
+	liveTime := time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)
+	a.Record(1, client.Message{Type: client.BiliBiliRoomInfo, Data: &bilibili.RoomInfo{LiveTime: liveTime}})
+	current := SessionPath(dir, 1, SessionName(liveTime, a.started))
+	if err := os.Chtimes(current, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(recent, old, old); err != nil {
+		t.Fatal(err)
+	}
+	a.prune()
+	if exists(recent) {
+		t.Error("session expired while running is not pruned")
+	}
+	if !exists(current) {
+		t.Error("session being written is pruned")
+	}
+}
@@ -0,0 +1,221 @@
+package archive
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+// Query 聊天记录的查询条件, 零值字段不参与过滤
+type Query struct {
+	Rooms   []int64
+	Types   []event.Type
+	UID     int64
+	User    string // 用户名包含, 不区分大小写
+	Keyword string // 内容包含, 不区分大小写
+	Regexp  *regexp.Regexp
+	Since   time.Time
+	Until   time.Time
+}
+
+// Match 事件是否满足所有条件
+func (q *Query) Match(e *event.Event) bool {
+	switch {
+	case len(q.Rooms) > 0 && !slices.Contains(q.Rooms, e.Room):
+	case len(q.Types) > 0 && !slices.Contains(q.Types, e.Type):
+	case q.UID != 0 && e.UID != q.UID:
+	case q.User != "" && !containsFold(e.User, q.User):
+	case q.Keyword != "" && !containsFold(e.Content, q.Keyword):
+	case q.Regexp != nil && !q.Regexp.MatchString(e.Content):
+	case !q.Since.IsZero() && e.Timestamp.Before(q.Since):
+	case !q.Until.IsZero() && !e.Timestamp.Before(q.Until):
+	default:
+		return true
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// ParseQuery 解析搜索框中的查询, 以空格分隔,
+// 支持 user: uid: type: room: since: until: re: 前缀, 其余部分作为关键字, 如
+//
+//	user:老粉丝 type:danmaku,superchat since:7d 晚上好
+func ParseQuery(s string, now time.Time) (Query, error) {
+	var (
+		q        Query
+		keywords []string
+	)
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			keywords = append(keywords, field)
+			continue
+		}
+
+		var err error
+		switch key {
+		case "user":
+			q.User = value
+		case "uid":
+			q.UID, err = strconv.ParseInt(value, 10, 64)
+		case "type":
+			q.Types, err = ParseTypes(value)
+		case "room":
+			for id := range strings.SplitSeq(value, ",") {
+				var room int64
+				if room, err = strconv.ParseInt(id, 10, 64); err != nil {
+					break
+				}
+				q.Rooms = append(q.Rooms, room)
+			}
+		case "since":
+			q.Since, err = ParseTime(value, now)
+		case "until":
+			q.Until, err = ParseTime(value, now)
+		case "re":
+			q.Regexp, err = regexp.Compile(value)
+		default:
+			keywords = append(keywords, field)
+		}
+		if err != nil {
+			return q, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	q.Keyword = strings.Join(keywords, " ")
+	return q, nil
+}
+
+// ParseTypes 解析以逗号分隔的事件类型
+func ParseTypes(s string) ([]event.Type, error) {
+	var types []event.Type
+	for t := range strings.SplitSeq(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !archived[event.Type(t)] {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+		types = append(types, event.Type(t))
+	}
+	return types, nil
+}
+
+// ParseTime 解析绝对时间或相对于 now 的时长, 如 2006-01-02, 2006-01-02 15:04, 90m, 7d
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", "2006-01-02T15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// Result 一条匹配的事件及其前后的上下文
+type Result struct {
+	Session Session
+	Event   event.Event
+	Before  []event.Event
+	After   []event.Event
+}
+
+// Search 在 dir 下的所有场次中查找满足 q 的事件, 每条结果附带前后各 context 条事件,
+// 结果按时间排序, limit 大于 0 时只保留最新的 limit 条
+func Search(dir string, q Query, context, limit int) ([]Result, error) {
+	sessions, err := Sessions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*Result
+	for _, s := range sessions {
+		if len(q.Rooms) > 0 && !slices.Contains(q.Rooms, s.Room) {
+			continue
+		}
+		// 文件最后写入时间早于起始时间, 其中的事件都不满足条件
+		if !q.Since.IsZero() && s.ModTime.Before(q.Since) {
+			continue
+		}
+
+		// 场次内的事件按时间写入, 只需保留最后 limit 条匹配
+		var (
+			matches = ring{n: limit}
+			before  []event.Event
+			pending []*Result
+		)
+		err := ReadFile(s.Path, func(e event.Event) error {
+			pending = slices.DeleteFunc(pending, func(r *Result) bool {
+				r.After = append(r.After, e)
+				return len(r.After) >= context
+			})
+			if q.Match(&e) {
+				r := &Result{Session: s, Event: e, Before: slices.Clone(before)}
+				matches.push(r)
+				if context > 0 {
+					pending = append(pending, r)
+				}
+			}
+			if context > 0 {
+				before = append(before, e)
+				if len(before) > context {
+					before = before[1:]
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, matches.items()...)
+		slices.SortStableFunc(results, func(a, b *Result) int {
+			return a.Event.Timestamp.Compare(b.Event.Timestamp)
+		})
+		if limit > 0 && len(results) > limit {
+			results = slices.Delete(results, 0, len(results)-limit)
+		}
+	}
+
+	out := make([]Result, len(results))
+	for i, r := range results {
+		out[i] = *r
+	}
+	return out, nil
+}
+
+// ring 保留最后加入的 n 条结果, n 不大于 0 时不限制数量
+type ring struct {
+	n     int
+	start int
+	buf   []*Result
+}
+
+func (r *ring) push(v *Result) {
+	if r.n <= 0 || len(r.buf) < r.n {
+		r.buf = append(r.buf, v)
+		return
+	}
+	r.buf[r.start] = v
+	r.start = (r.start + 1) % r.n
+}
+
+// items 按加入顺序返回保留的结果
+func (r *ring) items() []*Result {
+	return append(slices.Clone(r.buf[r.start:]), r.buf[:r.start]...)
+}
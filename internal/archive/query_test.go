@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+// writeEvents 将事件写入场次文件
+func writeEvents(t *testing.T, dir string, roomID int64, session string, events ...event.Event) {
+	t.Helper()
+	path := SessionPath(dir, roomID, session)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		base = time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local)
+	)
+	danmaku := func(room int64, sec int, content string) event.Event {
+		return event.Event{Type: event.TypeDanmaku, Room: room, Content: content, Timestamp: base.Add(time.Duration(sec) * time.Second)}
+	}
+	// 两个直播间的场次时间交错, 结果需要跨场次按时间合并
+	writeEvents(t, dir, 1, "20240101-200000",
+		danmaku(1, 0, "hi a"), danmaku(1, 2, "other"), danmaku(1, 4, "hi b"), danmaku(1, 8, "hi c"))
+	writeEvents(t, dir, 2, "20240101-200000",
+		danmaku(2, 1, "hi d"), danmaku(2, 5, "hi e"), danmaku(2, 6, "other"), danmaku(2, 9, "hi f"))
+
+	q := Query{Keyword: "hi"}
+	all, err := Search(dir, q, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, r := range all {
+		contents = append(contents, r.Event.Content)
+	}
+	if want := []string{"hi a", "hi d", "hi b", "hi e", "hi c", "hi f"}; !reflect.DeepEqual(contents, want) {
+		t.Fatalf("Search contents = %v, want %v", contents, want)
+	}
+
+	for _, limit := range []int{1, 3, 6, 10} {
+		got, err := Search(dir, q, 1, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := all[max(len(all)-limit, 0):]
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Search limit %d = %+v, want %+v", limit, got, want)
+		}
+	}
+}
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		n    int
+		push int
+		want []int
+	}{
+		{n: 0, push: 4, want: []int{0, 1, 2, 3}},
+		{n: 3, push: 2, want: []int{0, 1}},
+		{n: 3, push: 3, want: []int{0, 1, 2}},
+		{n: 3, push: 7, want: []int{4, 5, 6}},
+	}
+	for _, tt := range tests {
+		r := ring{n: tt.n}
+		for i := range tt.push {
+			r.push(&Result{Event: event.Event{Num: int64(i)}})
+		}
+		var got []int
+		for _, v := range r.items() {
+			got = append(got, int(v.Event.Num))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ring n %d push %d = %v, want %v", tt.n, tt.push, got, tt.want)
+		}
+	}
+}
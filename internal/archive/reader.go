@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+// Session 一个直播间的一场记录
+type Session struct {
+	Room    int64
+	Name    string
+	Path    string
+	ModTime time.Time
+}
+
+// LiveTime 场次的开播时间, 离线场次返回零值
+func (s Session) LiveTime() time.Time {
+	t, err := time.ParseInLocation(sessionLayout, s.Name, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// StartTime 场次的开始时间, 离线场次为程序的启动时间, 无法解析时返回零值
+func (s Session) StartTime() time.Time {
+	t, err := time.ParseInLocation(sessionLayout, strings.TrimPrefix(s.Name, offlinePrefix), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Sessions 列出 dir 下的所有场次, 按直播间与场次开始时间排序
+func Sessions(dir string) ([]Session, error) {
+	rooms, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []Session
+	for _, rd := range rooms {
+		roomID, err := strconv.ParseInt(rd.Name(), 10, 64)
+		if !rd.IsDir() || err != nil {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, rd.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != Ext {
+				continue
+			}
+			fi, err := f.Info()
+			if err != nil {
+				return nil, err
+			}
+			sessions = append(sessions, Session{
+				Room:    roomID,
+				Name:    strings.TrimSuffix(f.Name(), Ext),
+				Path:    filepath.Join(dir, rd.Name(), f.Name()),
+				ModTime: fi.ModTime(),
+			})
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Or(cmp.Compare(a.Room, b.Room), a.StartTime().Compare(b.StartTime()), strings.Compare(a.Name, b.Name))
+	})
+	return sessions, nil
+}
+
+// ReadFile 按顺序读取记录文件中的事件, fn 返回错误时停止读取, 无法解析的行被跳过
+func ReadFile(path string, fn func(e event.Event) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e event.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,28 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionsOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"offline-20240103-100000", "20240102-200000", "offline-20240101-090000", "20240104-200000"} {
+		writeSession(t, dir, 1, name, time.Now())
+	}
+
+	sessions, err := Sessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range sessions {
+		names = append(names, s.Name)
+	}
+	// 离线场次按启动时间与开播的场次一起排序, 而不是排在所有开播场次之后
+	want := []string{"offline-20240101-090000", "20240102-200000", "offline-20240103-100000", "20240104-200000"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Sessions = %v, want %v", names, want)
+	}
+}
@@ -1,74 +1,133 @@
 package bilibili
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
-	"os"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
 	"github.com/BYT0723/go-tools/logx"
 	"github.com/BYT0723/go-tools/transport/httpx"
 	"github.com/gorilla/websocket"
 	"github.com/iyear/biligo"
 	"github.com/tidwall/gjson"
-	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+
+	defaultProtover = 3
+	// defaultHeartbeatInterval 弹幕连接的心跳间隔, 心跳回复中包含人气值
+	defaultHeartbeatInterval = 30 * time.Second
+	// msgQueueSize 等待使用方读取的消息数上限, 超出后按 OverflowPolicy 处理
+	msgQueueSize = 1024
 )
 
 type Client struct {
 	roomID  uint32
 	roomUID uint32
 	cli     *biligo.BiliClient
+
 	conn    *websocket.Conn
+	connMu  sync.Mutex
+	connCf  context.CancelFunc
+	header  http.Header
+	hostIdx int
+
+	heartbeatInterval time.Duration
 
 	cookie    string
 	cookies   map[string]string
 	uid       uint32
 	wbiImgURL string
 	wbiSubURL string
-
-	msgCh chan client.Message
+	protover  uint8
+	endpoints Endpoints
+
+	encoder  *packet.Encoder
+	decoder  *packet.Decoder
+	registry *Registry
+	recorder *Recorder
+
+	overflow OverflowPolicy
+	queue    *queue
+
+	// 发送队列
+	sendCh       chan *sendJob
+	sendSeq      atomic.Uint64
+	sendInterval time.Duration
+	sendRetries  int
+	maxLength    int // 0 表示首次发送时向服务端查询
+
+	// 等待服务端广播的已发送弹幕
+	echoMu      sync.Mutex
+	echoes      []*pendingEcho
+	echoTimeout time.Duration
+
+	// 直播间表情包, 启动时加载, 其中的文字表情加入 emotes
+	emoteMu       sync.Mutex
+	emoticons     []client.EmoticonPackage
+	emotes        *EmoteTable
+	emoteCacheDir string
+	emoteCacheAge time.Duration
 
 	ctx context.Context
 	cf  context.CancelFunc
 }
 
-func NewClient(cookie string, roomID uint32) (c *Client, err error) {
+func NewClient(cookie string, roomID uint32, opts ...Option) (c *Client, err error) {
 	cookies, err := parseCookie(cookie)
 	if err != nil {
 		return c, err
 	}
 
-	cli, err := biligo.NewBiliClient(&biligo.BiliSetting{Auth: &biligo.CookieAuth{
+	c = &Client{
+		roomID:    roomID,
+		cookie:    cookie,
+		cookies:   cookies,
+		protover:  defaultProtover,
+		endpoints: DefaultEndpoints,
+		encoder:   packet.NewEncoder(),
+		decoder:   packet.NewDecoder(),
+		registry:  DefaultRegistry,
+
+		heartbeatInterval: defaultHeartbeatInterval,
+
+		sendCh:       make(chan *sendJob, sendQueueSize),
+		sendInterval: defaultSendInterval,
+		sendRetries:  defaultSendRetries,
+		echoTimeout:  defaultEchoTimeout,
+
+		emotes: NewEmoteTable(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.queue = newQueue(c.overflow, msgQueueSize)
+
+	c.cli, err = biligo.NewBiliClient(&biligo.BiliSetting{Auth: &biligo.CookieAuth{
 		SESSDATA:        cookies["SESSDATA"],
 		DedeUserID:      cookies["DedeUserID"],
 		DedeUserIDCkMd5: cookies["DedeUserID__ckMd5"],
 		BiliJCT:         cookies["bili_jct"],
-	}, DebugMode: false})
+	}, Client: &http.Client{Transport: newEndpointTransport(c.endpoints)}, DebugMode: false})
 	if err != nil {
-		return c, err
-	}
-
-	c = &Client{
-		roomID:  roomID,
-		cli:     cli,
-		cookie:  cookie,
-		cookies: cookies,
-		msgCh:   make(chan client.Message, 1024),
+		return nil, err
 	}
 	return
 }
 
 func (c *Client) Start(ctx context.Context) (err error) {
 	c.ctx, c.cf = context.WithCancel(ctx)
+	go c.queue.run(c.ctx)
 
 	if err = c.connect(); err != nil {
 		return
@@ -76,6 +135,12 @@ func (c *Client) Start(ctx context.Context) (err error) {
 
 	// 获取房间历史弹幕
 	go c.getHistoryDanmaku()
+	// 加载直播间表情包
+	go func() {
+		if _, err := c.Emoticons(); err != nil {
+			logx.Errorf("load emoticons, err: %v", err)
+		}
+	}()
 	// 定时获取房间信息
 	go func() {
 		var (
@@ -99,6 +164,8 @@ func (c *Client) Start(ctx context.Context) (err error) {
 	}()
 
 	go c.handlerMsg()
+	go c.runSender()
+	go c.watchEchoes()
 	go c.videoHeartBeat()
 
 	return
@@ -108,28 +175,30 @@ func (c *Client) Stop() error {
 	if c.cf != nil {
 		c.cf()
 	}
+	c.closeConn()
 	return nil
 }
 
 func (c *Client) Receive() <-chan client.Message {
-	return c.msgCh
+	return c.queue.out
 }
 
-func (c *Client) Send(content string) error {
-	return c.cli.LiveSendDanmaku(int64(c.roomID), 16777215, 25, 1, content, 0)
+// Dropped 因消息队列写满被丢弃的消息数
+func (c *Client) Dropped() DropStats {
+	return c.queue.Stats()
 }
 
 func (c *Client) connect() error {
-	header := http.Header{
+	c.header = http.Header{
 		"Cookie":     []string{c.cookie},
-		"Origin":     []string{"https://live.bilibili.com"},
+		"Origin":     []string{c.endpoints.Origin},
 		"User-Agent": []string{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
 	}
-	header.Set("Accept", "*/*")
-	header.Set("Accept-Language", "zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2")
+	c.header.Set("Accept", "*/*")
+	c.header.Set("Accept-Language", "zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2")
 
 	if c.uid == 0 {
-		resp, err := httpx.Getx(c.ctx, "https://api.bilibili.com/x/web-interface/nav", httpx.WithHeader(header))
+		resp, err := httpx.Getx(c.ctx, c.endpoints.API+"/x/web-interface/nav", httpx.WithHeader(c.header))
 		if err != nil {
 			return fmt.Errorf("failed to get user_id, err: %v", err)
 		}
@@ -149,32 +218,109 @@ func (c *Client) connect() error {
 		c.uid = uint32(gjson.GetBytes(resp.Body, "data.mid").Int())
 	}
 
-	hosts, token, err := c.getRoomStreamAddr()
+	return c.dial()
+}
+
+// dial 获取新的 token 与 host 列表, 从 hostIdx 开始依次尝试连接并鉴权
+func (c *Client) dial() error {
+	addrs, token, err := c.getRoomStreamAddr()
 	if err != nil {
 		return err
 	}
 
-	if len(hosts) == 0 {
-		return errors.New("failed to get wss host")
+	if len(addrs) == 0 {
+		return errors.New("failed to get stream address")
 	}
 
-	for _, h := range hosts {
-		c.conn, _, err = websocket.DefaultDialer.Dial(fmt.Sprintf("wss://%s/sub", h), header)
+	var conn *websocket.Conn
+	for i := range addrs {
+		idx := (c.hostIdx + i) % len(addrs)
+		conn, _, err = websocket.DefaultDialer.DialContext(c.ctx, addrs[idx], c.header)
 		if err == nil {
+			c.hostIdx = idx
 			break
 		}
 	}
-	if c.conn == nil {
+	if conn == nil {
 		return fmt.Errorf("websocket connect err: %v", err)
 	}
+
+	c.closeConn()
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+
 	if err := c.sendAuth(token); err != nil {
+		c.closeConn()
 		return err
 	}
-	go c.connHeartBeat()
+
+	ctx, cf := context.WithCancel(c.ctx)
+	c.connMu.Lock()
+	c.connCf = cf
+	c.connMu.Unlock()
+
+	go c.connHeartBeat(ctx)
 	return nil
 }
 
-// fuck 每次启动总容易失败panic
+// closeConn 关闭当前连接并停止其心跳
+func (c *Client) closeConn() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if c.connCf != nil {
+		c.connCf()
+		c.connCf = nil
+	}
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+}
+
+// reconnect 以指数退避加随机抖动的方式重连, 直到成功或 ctx 结束
+func (c *Client) reconnect(cause error) error {
+	c.closeConn()
+
+	delay := reconnectMinDelay
+	for attempt := 1; ; attempt++ {
+		// 抖动范围 [delay/2, delay)
+		wait := delay/2 + rand.N(delay/2)
+
+		c.queue.Push(client.Message{
+			Type: client.BiliBiliConnState,
+			Data: &ConnState{
+				State:   ConnReconnecting,
+				Attempt: attempt,
+				Delay:   wait,
+				Err:     cause,
+			},
+		})
+
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(wait):
+		}
+
+		// 换下一个 host 重试
+		c.hostIdx++
+		if cause = c.dial(); cause == nil {
+			c.queue.Push(client.Message{
+				Type: client.BiliBiliConnState,
+				Data: &ConnState{
+					State:   ConnReconnected,
+					Attempt: attempt,
+				},
+			})
+			return nil
+		}
+		logx.Errorf("reconnect attempt %d, err: %v", attempt, cause)
+
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
 func (c *Client) handlerMsg() {
 	for {
 		select {
@@ -183,113 +329,27 @@ func (c *Client) handlerMsg() {
 		default:
 			_, rawMsg, err := c.conn.ReadMessage()
 			if err != nil {
+				if c.ctx.Err() != nil {
+					return
+				}
 				logx.Errorf("receiveRawMsg, err: %v", err)
-				return
+				if err := c.reconnect(err); err != nil {
+					return
+				}
+				continue
 			}
 
-			version := binary.BigEndian.Uint16(rawMsg[6:8])
-			if len(rawMsg) >= 8 && version == 2 {
-				for _, msg := range splitMsg(zlibUnCompress(rawMsg[16:])) {
-					var (
-						body = gjson.ParseBytes(msg[16:])
-						dmk  = &Danmaku{}
-						cmd  = body.Get("cmd").String()
-					)
-
-					if os.Getenv("BILICHAT_DEBUG") == "1" {
-						_ = os.MkdirAll("danmaku", os.ModePerm)
-						_ = os.WriteFile(fmt.Sprintf("danmaku/%s-%s.json", cmd, time.Now().Format(time.RFC3339)), msg[16:], os.ModePerm)
-					}
-					switch cmd {
-					case "DANMU_MSG":
-						dmk.Author = body.Get("info.2.1").String()
-						dmk.Content = body.Get("info.1").String()
-						if medal := body.Get("info.0.15.user.medal"); medal.IsObject() {
-							dmk.Medal = &Medal{
-								Level: int(medal.Get("level").Int()),
-								Name:  medal.Get("name").String(),
-							}
-						}
-					case "SUPER_CHAT_MESSAGE", "SUPER_CHAT_MESSAGE_JPN":
-						dmk.Author = fmt.Sprintf("%s [¥ %d]",
-							body.Get("data.user_info.uname").String(),
-							body.Get("data.price").Int(),
-						)
-						dmk.Content = body.Get("data.message").String()
-					case "COMBO_SEND":
-						dmk.Author = body.Get("data.r_uname").String()
-						dmk.Content = fmt.Sprintf(
-							"%s %d * %s",
-							body.Get("data.action").String(),
-							body.Get("data.combo_num").Int(),
-							body.Get("data.gift_name").String(),
-						)
-					case "SEND_GIFT":
-						dmk.Author = body.Get("data.uname").String()
-						dmk.Content = fmt.Sprintf(
-							"%s %d * %s",
-							body.Get("data.action").String(),
-							body.Get("data.num").Int(),
-							body.Get("data.giftName").String(),
-						)
-					case "GUARD_BUY":
-						dmk.Author = body.Get("data.username").String()
-						dmk.Content = fmt.Sprintf(
-							"%d * %s",
-							body.Get("data.num").Int(),
-							body.Get("data.gift_name").String(),
-						)
-					case "INTERACT_WORD":
-						dmk.Author = body.Get("data.uname").String()
-						dmk.Content = "进入直播间"
-					case "INTERACT_WORD_V2":
-						// NOTE: 将data.pb 使用如下命令进行解析
-						// echo "$data.pb" | base64 -d | protoc --decode_raw
-						data, err := base64.StdEncoding.DecodeString(body.Get("data.pb").String())
-						if err != nil {
-							logx.Errorf("base64 decode INTERACT_WORD_V2 err: %v", err)
-							continue
-						}
-
-						for len(data) > 0 {
-							num, typ, n := protowire.ConsumeTag(data)
-							if n < 0 || n > len(data) {
-								break
-							}
-							data = data[n:]
-
-							if num == 2 && typ == protowire.BytesType {
-								username, _ := protowire.ConsumeString(data)
-								dmk.Author = username
-								dmk.Content = "进入直播间"
-								data = data[len(data):]
-							}
-						}
-					case "WATCHED_CHANGE":
-						dmk.Content = body.Get("data.text_large").String()
-					case "LIKE_INFO_V3_UPDATE":
-						dmk.Content = body.Get("data.click_count").String()
-					case "ONLINE_RANK_COUNT":
-						dmk.Content = body.Get("data.online_count_text").String()
-					default: // "LIVE" "ACTIVITY_BANNER_UPDATE_V2" "ONLINE_RANK_COUNT" "ONLINE_RANK_TOP3" "ONLINE_RANK_V2" "PANEL" "PREPARING" "WIDGET_BANNER" "LIVE_INTERACTIVE_GAME"
-						continue
-					}
-					// GUARD_BUY        上舰长
-					// USER_TOAST_MSG   续费了舰长
-					// NOTICE_MSG       在本房间续费了舰长
-					// ANCHOR_LOT_START 天选之人开始完整信息
-					// ANCHOR_LOT_END   天选之人获奖id
-					// ANCHOR_LOT_AWARD 天选之人获奖完整信息
-
-					dmk.Content = strings.ReplaceAll(dmk.Content, "\r", "")
-					dmk.Type = cmd
-					dmk.T = time.Now()
-					c.msgCh <- client.Message{
-						Type: client.BiliBiliDanmaku,
-						Data: dmk,
-					}
+			now := time.Now()
+			if c.recorder != nil {
+				if err := c.recorder.Write(now, rawMsg); err != nil {
+					logx.Errorf("record frame, err: %v", err)
 				}
 			}
+
+			dispatchFrame(c.decoder, c.registry, now, rawMsg, func(msg client.Message) {
+				c.matchEcho(msg)
+				c.queue.Push(msg)
+			})
 		}
 	}
 }
@@ -297,7 +357,7 @@ func (c *Client) handlerMsg() {
 func (c *Client) syncRoomInfo() {
 	roomInfo := new(RoomInfo)
 
-	resp, err := httpx.Getx(c.ctx, "https://api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo", httpx.WithPayload(map[string]any{
+	resp, err := httpx.Getx(c.ctx, c.endpoints.Live+"/xlive/web-room/v1/index/getRoomBaseInfo", httpx.WithPayload(map[string]any{
 		"room_ids": c.roomID,
 		"req_biz":  "web_room_componet",
 	}))
@@ -322,20 +382,21 @@ func (c *Client) syncRoomInfo() {
 	roomInfo.Attention = info.Get("attention").Int()
 	roomInfo.Attention = gjson.Get(string(resp.Body), "data.attention").Int()
 	if _time, err := time.ParseInLocation(time.DateTime, info.Get("live_time").String(), time.Local); err == nil {
+		roomInfo.LiveTime = _time
 		dur := time.Since(_time)
 		if dur > 0 {
 			roomInfo.Uptime = dur
 		}
 	}
 
-	c.msgCh <- client.Message{
+	c.queue.Push(client.Message{
 		Type: client.BiliBiliRoomInfo,
 		Data: roomInfo,
-	}
+	})
 }
 
 func (c *Client) syncRank() {
-	resp, err := httpx.Getx(c.ctx, "https://api.live.bilibili.com/xlive/general-interface/v1/rank/getOnlineGoldRank", httpx.WithPayload(map[string]any{
+	resp, err := httpx.Getx(c.ctx, c.endpoints.Live+"/xlive/general-interface/v1/rank/getOnlineGoldRank", httpx.WithPayload(map[string]any{
 		"ruid":     c.roomUID,
 		"roomId":   c.roomID,
 		"page":     1,
@@ -363,42 +424,26 @@ func (c *Client) syncRank() {
 		rank = append(rank, &user)
 	}
 
-	c.msgCh <- client.Message{
+	c.queue.Push(client.Message{
 		Type: client.BiliBiliRankInfo,
 		Data: rank,
-	}
+	})
 }
 
-var pktSeq uint32
-
-func (c *Client) sendPackage(ver uint16, typeID uint32, data []byte) (err error) {
-	packetHead := new(bytes.Buffer)
-
-	for _, v := range []any{
-		uint32(len(data) + 16),
-		uint16(16),
-		ver,
-		typeID,
-		pktSeq,
-	} {
-		if err = binary.Write(packetHead, binary.BigEndian, v); err != nil {
-			return err
-		}
-	}
+func (c *Client) sendPacket(version uint16, op uint32, body []byte) error {
+	data := c.encoder.Encode(version, op, body)
 
-	atomic.AddUint32(&pktSeq, 1)
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
 
-	sendData := append(packetHead.Bytes(), data...)
-
-	err = c.conn.WriteMessage(websocket.BinaryMessage, sendData)
-	return err
+	return c.conn.WriteMessage(websocket.BinaryMessage, data)
 }
 
 func (c *Client) sendAuth(token string) (err error) {
 	hsInfo := handShakeInfo{
 		UID:      c.uid,
 		Roomid:   c.roomID,
-		Protover: 2,
+		Protover: c.protover,
 		Platform: "web",
 		Type:     2,
 		Key:      token,
@@ -409,7 +454,7 @@ func (c *Client) sendAuth(token string) (err error) {
 		return err
 	}
 
-	if err = c.sendPackage(1, 7, body); err != nil {
+	if err = c.sendPacket(packet.VersionInt32, packet.OpAuth, body); err != nil {
 		return err
 	}
 
@@ -417,16 +462,17 @@ func (c *Client) sendAuth(token string) (err error) {
 	if err != nil {
 		return err
 	}
-	if len(rawMsg) < 16 {
-		return errors.New("invalid auth response")
+	pkt, _, err := packet.Unmarshal(rawMsg)
+	if err != nil {
+		return fmt.Errorf("invalid auth response: %v", err)
 	}
-	if code := binary.BigEndian.Uint32(rawMsg[8:12]); code != 8 {
-		return fmt.Errorf("invalid auth response op code: %v", code)
+	if pkt.Op != packet.OpAuthReply {
+		return fmt.Errorf("invalid auth response op code: %v", pkt.Op)
 	}
 
-	if code := gjson.GetBytes(rawMsg[16:], "code"); code.Exists() {
+	if code := gjson.GetBytes(pkt.Body, "code"); code.Exists() {
 		if code.Int() != 0 {
-			return fmt.Errorf("invalid auth response code: %v, resp: %s", code.Int(), rawMsg[16:])
+			return fmt.Errorf("invalid auth response code: %v, resp: %s", code.Int(), pkt.Body)
 		}
 	} else {
 		return errors.New("invalid auth response, not found code")
@@ -435,7 +481,7 @@ func (c *Client) sendAuth(token string) (err error) {
 }
 
 func (c *Client) getHistoryDanmaku() {
-	resp, err := httpx.Getx(c.ctx, "https://api.live.bilibili.com/xlive/web-room/v1/dM/gethistory", httpx.WithPayload(map[string]any{"roomid": c.roomID}))
+	resp, err := httpx.Getx(c.ctx, c.endpoints.Live+"/xlive/web-room/v1/dM/gethistory", httpx.WithPayload(map[string]any{"roomid": c.roomID}))
 	if err != nil {
 		logx.Errorf("getHistoryDanmaku, err: %v", err)
 		return
@@ -448,15 +494,15 @@ func (c *Client) getHistoryDanmaku() {
 	histories := gjson.GetBytes(resp.Body, "data.room").Array()
 	for _, history := range histories {
 		t, _ := time.Parse(time.DateTime, history.Get("timeline").String())
-		c.msgCh <- client.Message{
+		c.queue.Push(client.Message{
 			Type: client.BiliBiliDanmaku,
 			Data: &Danmaku{
+				UID:     history.Get("uid").Int(),
 				Author:  history.Get("nickname").String(),
 				Content: history.Get("text").String(),
-				Type:    "DANMU_MSG",
 				T:       t,
 			},
-		}
+		})
 	}
 }
 
@@ -479,31 +525,33 @@ func (c *Client) videoHeartBeat() {
 	}
 }
 
-func (c *Client) connHeartBeat() {
+func (c *Client) connHeartBeat(ctx context.Context) {
 	var (
-		ticker  = time.NewTicker(30 * time.Second)
+		ticker  = time.NewTicker(c.heartbeatInterval)
 		payload = []byte("[object Object]")
 	)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := c.sendPackage(1, 2, payload); err != nil {
+			if err := c.sendPacket(packet.VersionInt32, packet.OpHeartbeat, payload); err != nil {
 				logx.Error("send conn heart beat, err: ", err)
 			}
 		}
 	}
 }
 
-func (c *Client) getRoomStreamAddr() (hosts []string, token string, err error) {
+// getRoomStreamAddr 获取弹幕服务的 token 与可用的 WebSocket 地址
+func (c *Client) getRoomStreamAddr() (addrs []string, token string, err error) {
 	var (
 		header = http.Header{
 			"Cookie":          []string{c.cookie},
-			"Origin":          []string{"https://live.bilibili.com"},
+			"Origin":          []string{c.endpoints.Origin},
 			"User-Agent":      []string{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36"},
-			"Referer":         []string{fmt.Sprintf("https://live.bilibili.com/%d", c.roomID)},
+			"Referer":         []string{fmt.Sprintf("%s/%d", c.endpoints.Origin, c.roomID)},
 			"Accept":          []string{"*/*"},
 			"Accept-Language": []string{"zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2"},
 		}
@@ -518,28 +566,35 @@ func (c *Client) getRoomStreamAddr() (hosts []string, token string, err error) {
 
 	resp, err := httpx.Getx(
 		c.ctx,
-		"https://api.live.bilibili.com/xlive/web-room/v1/index/getDanmuInfo",
+		c.endpoints.Live+"/xlive/web-room/v1/index/getDanmuInfo",
 		httpx.WithHeader(header),
 		httpx.WithPayload(params),
 	)
 	if err != nil {
 		err = fmt.Errorf("failed to get token, err: %v", err)
-		return hosts, token, err
+		return addrs, token, err
 	}
 	if resp.Code != http.StatusOK || len(resp.Body) == 0 {
 		err = fmt.Errorf("failed to get token, status: %v", resp.Code)
-		return hosts, token, err
+		return addrs, token, err
 	}
 
 	if code := gjson.GetBytes(resp.Body, "code").Int(); code != 0 {
 		err = fmt.Errorf("failed to get token, code: %v", code)
-		return hosts, token, err
+		return addrs, token, err
 	}
 
 	token = gjson.GetBytes(resp.Body, "data.token").String()
+	if c.endpoints.Stream != "" {
+		return []string{c.endpoints.Stream}, token, err
+	}
 	gjson.GetBytes(resp.Body, "data.host_list").ForEach(func(key, value gjson.Result) bool {
-		hosts = append(hosts, fmt.Sprintf("%s:%d", value.Get("host").String(), value.Get("wss_port").Int()))
+		if c.endpoints.Insecure {
+			addrs = append(addrs, fmt.Sprintf("ws://%s:%d/sub", value.Get("host").String(), value.Get("ws_port").Int()))
+		} else {
+			addrs = append(addrs, fmt.Sprintf("wss://%s:%d/sub", value.Get("host").String(), value.Get("wss_port").Int()))
+		}
 		return true
 	})
-	return hosts, token, err
+	return addrs, token, err
 }
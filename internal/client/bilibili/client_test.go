@@ -0,0 +1,261 @@
+package bilibili
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/mock"
+	"github.com/BYT0723/go-tools/logx"
+)
+
+const waitTimeout = 5 * time.Second
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bilichat-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := logx.Init(logx.WithConf(&logx.Config{
+		Dir:   dir,
+		Name:  "test",
+		Ext:   "log",
+		Level: "info",
+	})); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startMock 启动模拟服务并连接客户端
+func startMock(t *testing.T, opts ...Option) (*mock.Server, *Client) {
+	t.Helper()
+	srv := newMock()
+	return srv, startClient(t, srv, opts...)
+}
+
+func newMock() *mock.Server {
+	srv := mock.NewServer()
+	srv.Interval = 50 * time.Millisecond
+	srv.BatchSize = 2
+	return srv
+}
+
+// startClient 以 h 作为服务端启动客户端, 用于在模拟服务之外修改部分接口的行为
+func startClient(t *testing.T, h http.Handler, opts ...Option) *Client {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	opts = append([]Option{
+		WithEndpoints(Endpoints{API: ts.URL, Live: ts.URL, Origin: ts.URL, Insecure: true}),
+		WithHeartbeatInterval(50 * time.Millisecond),
+		WithSendInterval(10 * time.Millisecond),
+		WithEchoTimeout(500 * time.Millisecond),
+	}, opts...)
+	c, err := NewClient("SESSDATA=mock; DedeUserID=10000; bili_jct=mock", 1000, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Stop() })
+	return c
+}
+
+// waitFor 读取消息直到 match 返回 true, 超时则测试失败
+func waitFor(t *testing.T, c *Client, desc string, match func(client.Message) bool) client.Message {
+	t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case msg := <-c.Receive():
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s", desc)
+			return client.Message{}
+		}
+	}
+}
+
+func isType(typ client.MessageType) func(client.Message) bool {
+	return func(msg client.Message) bool { return msg.Type == typ }
+}
+
+func TestClientReceive(t *testing.T) {
+	_, c := startMock(t)
+
+	// 房间信息在启动时获取, 先于弹幕到达
+	msg := waitFor(t, c, "room info", isType(client.BiliBiliRoomInfo))
+	if info := msg.Data.(*RoomInfo); info.Title == "" {
+		t.Errorf("room info = %+v, want title", info)
+	}
+
+	msg = waitFor(t, c, "danmaku", func(msg client.Message) bool {
+		d, ok := msg.Data.(*Danmaku)
+		return ok && d.UID != mock.UID
+	})
+	if d := msg.Data.(*Danmaku); d.Author == "" || d.Content == "" {
+		t.Errorf("danmaku = %+v, want author and content", d)
+	}
+
+	msg = waitFor(t, c, "gift", isType(client.BiliBiliGift))
+	if g := msg.Data.(*Gift); g.GiftName == "" || g.Num <= 0 {
+		t.Errorf("gift = %+v, want name and num", g)
+	}
+
+	msg = waitFor(t, c, "popularity", isType(client.BiliBiliPopularity))
+	if p := msg.Data.(*Popularity); p.Value < 0 {
+		t.Errorf("popularity = %d, want >= 0", p.Value)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	srv, c := startMock(t)
+	waitFor(t, c, "danmaku", isType(client.BiliBiliDanmaku))
+
+	srv.CloseStreams()
+	waitFor(t, c, "reconnecting", func(msg client.Message) bool {
+		s, ok := msg.Data.(*ConnState)
+		return ok && s.State == ConnReconnecting
+	})
+	waitFor(t, c, "reconnected", func(msg client.Message) bool {
+		s, ok := msg.Data.(*ConnState)
+		if ok && s.State == ConnReconnecting {
+			t.Errorf("reconnect attempt %d failed: %v", s.Attempt, s.Err)
+		}
+		return ok && s.State == ConnReconnected
+	})
+	waitFor(t, c, "danmaku after reconnect", isType(client.BiliBiliDanmaku))
+}
+
+func TestClientSend(t *testing.T) {
+	srv, c := startMock(t)
+	srv.ShadowWords = []string{"坏词"}
+
+	tests := []struct {
+		content   string
+		delivered bool
+	}{
+		{"你好", true},
+		{"坏词", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			id, err := c.Send(tt.content, client.SendOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 模拟服务先广播弹幕再返回发送结果, 两者的先后顺序不固定
+			var (
+				result *SendResult
+				echo   *SendEcho
+			)
+			for result == nil || echo == nil {
+				msg := waitFor(t, c, "send result and echo", func(msg client.Message) bool {
+					switch v := msg.Data.(type) {
+					case *SendResult:
+						return v.ID == id
+					case *SendEcho:
+						return v.ID == id
+					}
+					return false
+				})
+				switch v := msg.Data.(type) {
+				case *SendResult:
+					result = v
+				case *SendEcho:
+					echo = v
+				}
+			}
+
+			if !result.OK() || result.Content != tt.content {
+				t.Errorf("result = %+v, want OK with content %q", result, tt.content)
+			}
+			if echo.Delivered != tt.delivered {
+				t.Errorf("echo Delivered = %v, want %v", echo.Delivered, tt.delivered)
+			}
+		})
+	}
+}
+
+func TestClientEmoticons(t *testing.T) {
+	dir := t.TempDir()
+	_, c := startMock(t, WithEmoteCache(dir, time.Hour))
+
+	// 启动时加载表情包并写入缓存
+	path := EmoteCachePath(dir, 1000)
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		if _, ok := LoadEmoteCache(path, time.Hour); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for emote cache")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, ok := c.Emotes().Lookup("dog"); !ok {
+		t.Error("Emotes missing room emote [dog]")
+	}
+
+	pkgs, err := c.Emoticons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 {
+		t.Errorf("Emoticons got %d packages, want 2", len(pkgs))
+	}
+
+	// 无法连接服务时使用过期的缓存
+	ts := httptest.NewServer(mock.NewServer())
+	offline, err := NewClient("SESSDATA=mock", 1000,
+		WithEndpoints(Endpoints{API: ts.URL, Live: ts.URL}),
+		WithEmoteCache(dir, time.Nanosecond),
+		WithEmoteOverride(map[string]string{"吃瓜": "🥒"}),
+	)
+	ts.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个客户端的表情表相互独立, 加载表情包前不包含其他直播间的表情
+	if _, ok := offline.Emotes().Lookup("dog"); ok {
+		t.Error("Emotes of another client leaked before loading emoticons")
+	}
+	cached, err := offline.Emoticons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != len(pkgs) {
+		t.Errorf("offline Emoticons got %d packages, want %d", len(cached), len(pkgs))
+	}
+	if _, ok := offline.Emotes().Lookup("dog"); !ok {
+		t.Error("offline Emotes missing cached emote [dog]")
+	}
+	if repl, _ := offline.Emotes().Lookup("吃瓜"); repl != "🥒" {
+		t.Errorf("override [吃瓜] = %q, want 🥒", repl)
+	}
+	if repl, _ := c.Emotes().Lookup("吃瓜"); repl != "🍉" {
+		t.Error("override of one client applies to another")
+	}
+}
+
+func TestWithHeartbeatIntervalNonPositive(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		c := &Client{heartbeatInterval: defaultHeartbeatInterval}
+		WithHeartbeatInterval(d)(c)
+		if c.heartbeatInterval != defaultHeartbeatInterval {
+			t.Errorf("WithHeartbeatInterval(%v) = %v, want default %v", d, c.heartbeatInterval, defaultHeartbeatInterval)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package bilibili
+
+import "time"
+
+type ConnStateType int
+
+const (
+	ConnReconnecting ConnStateType = iota // 连接断开, 等待重连
+	ConnReconnected                       // 重连成功
+)
+
+type ConnState struct {
+	State   ConnStateType
+	Attempt int           // 第几次重连
+	Delay   time.Duration // 本次重连前的等待时间
+	Err     error         // 导致重连的错误
+}
@@ -4,11 +4,13 @@ import "time"
 
 type (
 	Danmaku struct {
+		UID     int64
 		Medal   *Medal
 		Author  string
 		Content string
-		Type    string
-		T       time.Time
+		// 图片表情的编号, 此时 Content 为表情名称
+		Emoticon string
+		T        time.Time
 	}
 	Medal struct {
 		Name  string
@@ -0,0 +1,114 @@
+package bilibili
+
+import (
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
+
+// defaultEchoTimeout 发送成功后等待服务端广播的时间, 超时视为被屏蔽
+const defaultEchoTimeout = 10 * time.Second
+
+// SendEcho 发送成功的弹幕是否被服务端广播回来
+type SendEcho struct {
+	ID        uint64
+	Part      int
+	Parts     int
+	Content   string
+	Delivered bool // false 表示超时未收到广播, 通常是被屏蔽
+	T         time.Time
+}
+
+// pendingEcho 等待广播的弹幕
+type pendingEcho struct {
+	result   *SendResult
+	deadline time.Time // 发送成功后开始计时, 发送中为零值
+}
+
+// expectEcho 在发送请求之前记录弹幕, 服务端可能在返回发送结果之前就广播了弹幕
+func (c *Client) expectEcho(result *SendResult) {
+	c.echoMu.Lock()
+	defer c.echoMu.Unlock()
+	c.echoes = append(c.echoes, &pendingEcho{result: result})
+}
+
+// settleEcho 得到发送结果后, 成功时开始等待广播计时, 失败时不再等待
+func (c *Client) settleEcho(result *SendResult) {
+	c.echoMu.Lock()
+	defer c.echoMu.Unlock()
+	for i, e := range c.echoes {
+		if e.result != result {
+			continue
+		}
+		if result.OK() {
+			e.deadline = time.Now().Add(c.echoTimeout)
+		} else {
+			c.echoes = append(c.echoes[:i], c.echoes[i+1:]...)
+		}
+		return
+	}
+}
+
+// matchEcho 收到自己发送的弹幕时标记为已送达, 在弹幕消息之前发出
+func (c *Client) matchEcho(msg client.Message) {
+	d, ok := msg.Data.(*Danmaku)
+	if !ok || c.uid == 0 || d.UID != int64(c.uid) {
+		return
+	}
+
+	c.echoMu.Lock()
+	var matched *pendingEcho
+	for i, e := range c.echoes {
+		if e.result.Content == d.Content && e.result.Options.Emoticon == d.Emoticon {
+			matched = e
+			c.echoes = append(c.echoes[:i], c.echoes[i+1:]...)
+			break
+		}
+	}
+	c.echoMu.Unlock()
+
+	if matched != nil {
+		c.queue.Push(client.Message{Type: client.BiliBiliSendEcho, Data: newSendEcho(matched.result, true)})
+	}
+}
+
+// watchEchoes 定期检查超时未收到广播的弹幕
+func (c *Client) watchEchoes() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case now := <-ticker.C:
+			var expired []*pendingEcho
+			c.echoMu.Lock()
+			remain := c.echoes[:0]
+			for _, e := range c.echoes {
+				if !e.deadline.IsZero() && now.After(e.deadline) {
+					expired = append(expired, e)
+				} else {
+					remain = append(remain, e)
+				}
+			}
+			c.echoes = remain
+			c.echoMu.Unlock()
+
+			for _, e := range expired {
+				c.queue.Push(client.Message{Type: client.BiliBiliSendEcho, Data: newSendEcho(e.result, false)})
+			}
+		}
+	}
+}
+
+func newSendEcho(r *SendResult, delivered bool) *SendEcho {
+	return &SendEcho{
+		ID:        r.ID,
+		Part:      r.Part,
+		Parts:     r.Parts,
+		Content:   r.Content,
+		Delivered: delivered,
+		T:         time.Now(),
+	}
+}
@@ -1,11 +1,20 @@
 package bilibili
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
 
 var emotePattern = regexp.MustCompile(`\[([^\]]+)\]`)
 
+// emoteFallback 直播间表情列表中没有对应 Unicode 的文字表情显示的标记
+const emoteFallback = "🖼"
+
+// emoteMap 内置的表情代码映射, 获取不到直播间表情列表时仍可使用
 var emoteMap = map[string]string{
-	// Bilibili live room emoticons (from GetEmoticons API)
 	"OH":      "😮",
 	"OK":      "👌",
 	"doge2":   "🐕",
@@ -121,10 +130,62 @@ var emoteMap = map[string]string{
 	"胜利":       "✌️",
 }
 
-func ReplaceEmoteCodes(text string) string {
+// EmoteTable 表情代码到 Unicode 的映射, 由内置映射、直播间表情列表与用户自定义映射合并而成
+type EmoteTable struct {
+	mu       sync.RWMutex
+	codes    map[string]string
+	override map[string]string
+}
+
+// NewEmoteTable 创建仅包含内置映射的表情表
+func NewEmoteTable() *EmoteTable {
+	t := &EmoteTable{codes: make(map[string]string, len(emoteMap))}
+	for code, repl := range emoteMap {
+		t.codes[code] = repl
+	}
+	return t
+}
+
+// SetOverride 设置用户自定义映射, 优先于其他来源
+func (t *EmoteTable) SetOverride(m map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.override = m
+}
+
+// AddEmoticons 加入直播间的文字表情, 已有映射的表情保持不变, 没有映射的显示为通用标记
+func (t *EmoteTable) AddEmoticons(pkgs []client.EmoticonPackage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Emoticons {
+			if e.Sticker || !strings.HasPrefix(e.Name, "[") || !strings.HasSuffix(e.Name, "]") {
+				continue
+			}
+			code := strings.TrimSuffix(strings.TrimPrefix(e.Name, "["), "]")
+			if _, ok := t.codes[code]; !ok {
+				t.codes[code] = emoteFallback
+			}
+		}
+	}
+}
+
+// Lookup 查找表情代码对应的 Unicode
+func (t *EmoteTable) Lookup(code string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if repl, ok := t.override[code]; ok {
+		return repl, true
+	}
+	repl, ok := t.codes[code]
+	return repl, ok
+}
+
+// Replace 在 [code] 前加上对应的 Unicode
+func (t *EmoteTable) Replace(text string) string {
 	return emotePattern.ReplaceAllStringFunc(text, func(match string) string {
 		code := emotePattern.FindStringSubmatch(match)[1]
-		if repl, ok := emoteMap[code]; ok {
+		if repl, ok := t.Lookup(code); ok {
 			return repl + match
 		}
 		return match
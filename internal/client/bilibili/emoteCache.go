@@ -0,0 +1,123 @@
+package bilibili
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/go-tools/logx"
+)
+
+// emoteCache 缓存到磁盘的直播间表情列表
+type emoteCache struct {
+	FetchedAt time.Time                `json:"fetched_at"`
+	Packages  []client.EmoticonPackage `json:"packages"`
+}
+
+// EmoteCachePath 直播间表情包在缓存目录 dir 下的文件路径
+func EmoteCachePath(dir string, roomID int64) string {
+	return filepath.Join(dir, strconv.FormatInt(roomID, 10)+".json")
+}
+
+// Emotes 该直播间的表情表, 不同直播间的表情互不影响
+func (c *Client) Emotes() *EmoteTable {
+	return c.emotes
+}
+
+// Emoticons 当前用户在直播间可用的表情包, 首次调用时加载并将其中的文字表情加入 Emotes.
+// 设置了缓存目录时优先使用未过期的缓存, 查询失败时使用过期的缓存
+func (c *Client) Emoticons() ([]client.EmoticonPackage, error) {
+	c.emoteMu.Lock()
+	defer c.emoteMu.Unlock()
+	if c.emoticons != nil {
+		return c.emoticons, nil
+	}
+
+	pkgs, err := c.loadEmoticons()
+	if err != nil {
+		return nil, err
+	}
+	c.emoticons = pkgs
+	c.emotes.AddEmoticons(pkgs)
+	return pkgs, nil
+}
+
+func (c *Client) loadEmoticons() ([]client.EmoticonPackage, error) {
+	if c.emoteCacheDir == "" {
+		return c.fetchEmoticons()
+	}
+
+	path := EmoteCachePath(c.emoteCacheDir, int64(c.roomID))
+	if pkgs, ok := LoadEmoteCache(path, c.emoteCacheAge); ok {
+		return pkgs, nil
+	}
+	pkgs, err := c.fetchEmoticons()
+	if err != nil {
+		if pkgs, ok := LoadEmoteCache(path, 0); ok {
+			return pkgs, nil
+		}
+		return nil, err
+	}
+	if err := SaveEmoteCache(path, pkgs); err != nil {
+		logx.Errorf("save emote cache, err: %v", err)
+	}
+	return pkgs, nil
+}
+
+// LoadEmoteCache 读取缓存的表情列表, maxAge 大于 0 时超过有效期的缓存视为不存在
+func LoadEmoteCache(path string, maxAge time.Duration) ([]client.EmoticonPackage, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var cache emoteCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(cache.FetchedAt) > maxAge {
+		return nil, false
+	}
+	return cache.Packages, true
+}
+
+// SaveEmoteCache 缓存表情列表
+func SaveEmoteCache(path string, pkgs []client.EmoticonPackage) error {
+	data, err := json.Marshal(emoteCache{FetchedAt: time.Now(), Packages: pkgs})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// 先写临时文件再替换, 避免读到写了一半的缓存
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadEmoteOverride 读取用户自定义的 表情代码 -> Unicode 映射, 代码可带方括号, 文件不存在时返回空映射
+func LoadEmoteOverride(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(raw))
+	for code, repl := range raw {
+		m[strings.TrimSuffix(strings.TrimPrefix(code, "["), "]")] = repl
+	}
+	return m, nil
+}
@@ -0,0 +1,83 @@
+package bilibili
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Endpoints bilibili 各服务的地址, 用于将 Client 指向本地的模拟服务
+type Endpoints struct {
+	API    string // 主站接口, 默认 https://api.bilibili.com
+	Live   string // 直播接口, 默认 https://api.live.bilibili.com
+	Origin string // 直播页面, 用作请求的 Origin 与 Referer, 默认 https://live.bilibili.com
+	// Stream 弹幕服务地址, 如 ws://127.0.0.1:8080/sub, 为空时使用 getDanmuInfo 返回的 host_list
+	Stream string
+	// Insecure 使用 host_list 中的 ws_port 以 ws:// 连接弹幕服务
+	Insecure bool
+}
+
+var DefaultEndpoints = Endpoints{
+	API:    "https://api.bilibili.com",
+	Live:   "https://api.live.bilibili.com",
+	Origin: "https://live.bilibili.com",
+}
+
+// withDefaults 使用 DefaultEndpoints 补全未设置的地址, 并去除末尾的 /
+func (e Endpoints) withDefaults() Endpoints {
+	if e.API == "" {
+		e.API = DefaultEndpoints.API
+	}
+	if e.Live == "" {
+		e.Live = DefaultEndpoints.Live
+	}
+	if e.Origin == "" {
+		e.Origin = DefaultEndpoints.Origin
+	}
+	e.API = strings.TrimSuffix(e.API, "/")
+	e.Live = strings.TrimSuffix(e.Live, "/")
+	e.Origin = strings.TrimSuffix(e.Origin, "/")
+	return e
+}
+
+// endpointTransport 将 biligo 内部写死的 bilibili 地址改写为 Endpoints 中的地址
+type endpointTransport struct {
+	hosts map[string]*url.URL
+	base  http.RoundTripper
+}
+
+func newEndpointTransport(e Endpoints) http.RoundTripper {
+	t := &endpointTransport{
+		hosts: make(map[string]*url.URL),
+		base:  http.DefaultTransport,
+	}
+	for def, target := range map[string]string{
+		DefaultEndpoints.API:  e.API,
+		DefaultEndpoints.Live: e.Live,
+	} {
+		if def == target {
+			continue
+		}
+		from, err1 := url.Parse(def)
+		to, err2 := url.Parse(target)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		t.hosts[from.Host] = to
+	}
+	return t
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	to, ok := t.hosts[req.URL.Host]
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = to.Scheme
+	req.URL.Host = to.Host
+	req.URL.Path = strings.TrimSuffix(to.Path, "/") + req.URL.Path
+	req.Host = to.Host
+	return t.base.RoundTrip(req)
+}
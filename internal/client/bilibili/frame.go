@@ -0,0 +1,88 @@
+package bilibili
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+	"github.com/BYT0723/go-tools/logx"
+	"github.com/tidwall/gjson"
+)
+
+// dispatchFrame 解码一个原始 WebSocket 帧, 并将解析出的消息交给 emit,
+// t 为帧的接收时间 (回放时为录制时间), 用于没有自带时间的事件
+func dispatchFrame(d *packet.Decoder, r *Registry, t time.Time, frame []byte, emit func(client.Message)) {
+	pkts, err := d.Decode(frame)
+	if err != nil {
+		logx.Errorf("decode packet, err: %v", err)
+	}
+	for _, pkt := range pkts {
+		switch pkt.Op {
+		case packet.OpMessage:
+		case packet.OpHeartbeatReply:
+			// 心跳回复的消息体为 4 字节的房间人气值
+			if len(pkt.Body) >= 4 {
+				emit(client.Message{
+					Type: client.BiliBiliPopularity,
+					Data: &Popularity{
+						Value: int64(binary.BigEndian.Uint32(pkt.Body)),
+						T:     t,
+					},
+				})
+			}
+			continue
+		default:
+			continue
+		}
+
+		var (
+			body = gjson.ParseBytes(pkt.Body)
+			cmd  = body.Get("cmd").String()
+		)
+
+		if os.Getenv("BILICHAT_DEBUG") == "1" {
+			_ = os.MkdirAll("danmaku", os.ModePerm)
+			_ = os.WriteFile(fmt.Sprintf("danmaku/%s-%s.json", cmd, t.Format(time.RFC3339)), pkt.Body, os.ModePerm)
+		}
+		msg, ok, err := r.Parse(body)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			logx.Errorf("parse %s, err: %v", cmd, err)
+			continue
+		}
+
+		stampTime(msg, t)
+		emit(msg)
+	}
+}
+
+// stampTime 为没有自带时间的内置事件填入 t
+func stampTime(msg client.Message, t time.Time) {
+	var p *time.Time
+	switch v := msg.Data.(type) {
+	case *Danmaku:
+		p = &v.T
+	case *SuperChat:
+		p = &v.T
+	case *Gift:
+		p = &v.T
+	case *GuardPurchase:
+		p = &v.T
+	case *UserEnter:
+		p = &v.T
+	case *StatsUpdate:
+		p = &v.T
+	case *RawEvent:
+		p = &v.T
+	default:
+		return
+	}
+	if p.IsZero() {
+		*p = t
+	}
+}
@@ -0,0 +1,47 @@
+package bilibili
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+)
+
+func TestDispatchFrameTime(t *testing.T) {
+	var (
+		received = time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+		frame    []byte
+	)
+	frame = append(frame, packet.Marshal(packet.Packet{Version: packet.VersionInt32, Op: packet.OpHeartbeatReply, Body: []byte{0, 0, 4, 0}})...)
+	for _, name := range []string{"WATCHED_CHANGE", "COMBO_SEND", "LIVE", "DANMU_MSG"} {
+		frame = append(frame, packet.Marshal(packet.Packet{Op: packet.OpMessage, Body: []byte(loadFixture(t, name).Raw)})...)
+	}
+
+	var got []time.Time
+	dispatchFrame(packet.NewDecoder(), DefaultRegistry, received, frame, func(msg client.Message) {
+		switch v := msg.Data.(type) {
+		case *Popularity:
+			got = append(got, v.T)
+		case *StatsUpdate:
+			got = append(got, v.T)
+		case *Gift:
+			got = append(got, v.T)
+		case *RawEvent:
+			got = append(got, v.T)
+		case *Danmaku:
+			got = append(got, v.T)
+		}
+	})
+
+	// 没有自带时间的事件使用帧的接收时间, 弹幕保留消息中的时间
+	want := []time.Time{received, received, received, received, time.UnixMilli(1700000000123)}
+	if len(got) != len(want) {
+		t.Fatalf("dispatchFrame emitted %d messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("message %d T = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package bilibili
+
+import "time"
+
+type (
+	// Gift 礼物, 价格单位为瓜子, 金瓜子 1000 = 1 元
+	Gift struct {
+		UID       int64
+		Author    string
+		Action    string // 投喂, 赠送等
+		GiftID    int64
+		GiftName  string
+		Num       int64
+		Price     int64  // 单价
+		TotalCoin int64  // 总价
+		CoinType  string // gold 金瓜子, silver 银瓜子
+		Combo     bool   // 是否为连击汇总
+		T         time.Time
+	}
+	// GuardPurchase 上舰, 价格单位为金瓜子
+	GuardPurchase struct {
+		UID        int64
+		Author     string
+		GuardLevel int // 1 总督, 2 提督, 3 舰长
+		GiftID     int64
+		GiftName   string
+		Num        int64
+		Price      int64
+		T          time.Time
+	}
+)
@@ -1,6 +1,19 @@
 package bilibili
 
-type interactWord struct {
-	UID  int64
-	User string
+import "time"
+
+type InteractType int
+
+const (
+	InteractEnter  InteractType = iota + 1 // 进入直播间
+	InteractFollow                         // 关注
+	InteractShare                          // 分享
+)
+
+// UserEnter 用户进入/关注/分享直播间
+type UserEnter struct {
+	UID    int64
+	Author string
+	Type   InteractType
+	T      time.Time
 }
@@ -0,0 +1,93 @@
+package bilibili
+
+import "time"
+
+type Option func(*Client)
+
+// WithProtover 设置鉴权时声明的协议版本, 2 为 zlib 压缩, 3 为 brotli 压缩
+func WithProtover(protover uint8) Option {
+	return func(c *Client) {
+		c.protover = protover
+	}
+}
+
+// WithHeartbeatInterval 设置弹幕连接的心跳间隔, 默认为 30 秒, 小于等于 0 时忽略.
+// 心跳回复中包含人气值, 缩短间隔可以更快地得到人气变化
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.heartbeatInterval = d
+		}
+	}
+}
+
+// WithRegistry 使用自定义的命令注册表, 默认为 DefaultRegistry
+func WithRegistry(r *Registry) Option {
+	return func(c *Client) {
+		c.registry = r
+	}
+}
+
+// WithEndpoints 设置 bilibili 各服务的地址, 未设置的字段使用 DefaultEndpoints
+func WithEndpoints(e Endpoints) Option {
+	return func(c *Client) {
+		c.endpoints = e.withDefaults()
+	}
+}
+
+// WithRecorder 将接收到的原始帧写入 Recorder, 用于之后通过 ReplayClient 回放
+func WithRecorder(r *Recorder) Option {
+	return func(c *Client) {
+		c.recorder = r
+	}
+}
+
+// WithOverflowPolicy 设置使用方读取不及时导致消息队列写满时的处理方式, 默认为 OverflowBlock
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(c *Client) {
+		c.overflow = p
+	}
+}
+
+// WithSendInterval 设置两条弹幕之间的最小发送间隔, 默认为 1 秒
+func WithSendInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.sendInterval = d
+	}
+}
+
+// WithMaxLength 设置单条弹幕的长度上限, 超出的弹幕拆分发送, 默认向服务端查询
+func WithMaxLength(n int) Option {
+	return func(c *Client) {
+		c.maxLength = n
+	}
+}
+
+// WithSendRetries 设置发送频率过快等可重试错误的重试次数, 默认为 2
+func WithSendRetries(n int) Option {
+	return func(c *Client) {
+		c.sendRetries = n
+	}
+}
+
+// WithEchoTimeout 设置发送成功后等待服务端广播的时间, 超时的弹幕视为被屏蔽, 默认为 10 秒
+func WithEchoTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.echoTimeout = d
+	}
+}
+
+// WithEmoteCache 将直播间表情包缓存到 dir 目录, maxAge 为缓存的有效期
+func WithEmoteCache(dir string, maxAge time.Duration) Option {
+	return func(c *Client) {
+		c.emoteCacheDir = dir
+		c.emoteCacheAge = maxAge
+	}
+}
+
+// WithEmoteOverride 设置用户自定义的 表情代码 -> Unicode 映射
+func WithEmoteOverride(m map[string]string) Option {
+	return func(c *Client) {
+		c.emotes.SetOverride(m)
+	}
+}
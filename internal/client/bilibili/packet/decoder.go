@@ -0,0 +1,106 @@
+package packet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultMaxBodySize 单个压缩数据包解压后的默认最大长度
+const DefaultMaxBodySize = 16 << 20
+
+// Decoder 解码数据包, 零值可用
+type Decoder struct {
+	// MaxBodySize 单个压缩数据包解压后的最大长度, 0 表示使用 DefaultMaxBodySize
+	MaxBodySize int
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Decode 拆分一个 WebSocket 帧中的所有数据包, 并递归解压 zlib 与 brotli 数据包,
+// 返回的数据包均未压缩. 遇到错误时返回错误之前已成功解码的数据包.
+func (d *Decoder) Decode(frame []byte) (pkts []Packet, err error) {
+	for len(frame) > 0 {
+		p, n, err := Unmarshal(frame)
+		if err != nil {
+			return pkts, err
+		}
+		frame = frame[n:]
+
+		switch p.Version {
+		case VersionZlib, VersionBrotli:
+			body, err := d.decompress(p.Version, p.Body)
+			if err != nil {
+				return pkts, err
+			}
+			nested, err := d.Decode(body)
+			pkts = append(pkts, nested...)
+			if err != nil {
+				return pkts, err
+			}
+		default:
+			pkts = append(pkts, p)
+		}
+	}
+	return pkts, nil
+}
+
+func (d *Decoder) decompress(version uint16, body []byte) ([]byte, error) {
+	var r io.Reader
+	switch version {
+	case VersionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("packet: zlib: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case VersionBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	}
+
+	limit := d.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	bs, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("packet: decompress version %d: %w", version, err)
+	}
+	if len(bs) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return bs, nil
+}
+
+// Unmarshal 解析 src 开头的单个数据包, 返回数据包与其占用的字节数
+func Unmarshal(src []byte) (p Packet, n int, err error) {
+	if len(src) < HeaderLen {
+		return p, 0, ErrShortPacket
+	}
+
+	var (
+		total     = int(binary.BigEndian.Uint32(src[0:4]))
+		headerLen = int(binary.BigEndian.Uint16(src[4:6]))
+	)
+	if headerLen < HeaderLen || headerLen > total {
+		return p, 0, ErrInvalidHeader
+	}
+	if total > len(src) {
+		return p, 0, ErrInvalidLength
+	}
+
+	p = Packet{
+		Version:  binary.BigEndian.Uint16(src[6:8]),
+		Op:       binary.BigEndian.Uint32(src[8:12]),
+		Sequence: binary.BigEndian.Uint32(src[12:16]),
+		Body:     src[headerLen:total],
+	}
+	return p, total, nil
+}
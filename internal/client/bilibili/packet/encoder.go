@@ -0,0 +1,72 @@
+package packet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encoder 编码数据包, 并为每个数据包分配递增的序列号, 可并发使用
+type Encoder struct {
+	seq atomic.Uint32
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Encode 编码单个数据包, 序列号由 Encoder 自动分配
+func (e *Encoder) Encode(version uint16, op uint32, body []byte) []byte {
+	return Marshal(Packet{
+		Version:  version,
+		Op:       op,
+		Sequence: e.seq.Add(1) - 1,
+		Body:     body,
+	})
+}
+
+// Marshal 按原样编码数据包
+func Marshal(p Packet) []byte {
+	buf := make([]byte, HeaderLen+len(p.Body))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	binary.BigEndian.PutUint16(buf[4:6], HeaderLen)
+	binary.BigEndian.PutUint16(buf[6:8], p.Version)
+	binary.BigEndian.PutUint32(buf[8:12], p.Op)
+	binary.BigEndian.PutUint32(buf[12:16], p.Sequence)
+	copy(buf[HeaderLen:], p.Body)
+	return buf
+}
+
+// Compress 将多个业务消息体编码为 version 0 的数据包后按 version 压缩,
+// 并包装为一个 OpMessage 数据包, version 仅支持 VersionZlib 与 VersionBrotli
+func (e *Encoder) Compress(version uint16, bodies ...[]byte) ([]byte, error) {
+	var raw bytes.Buffer
+	for _, body := range bodies {
+		raw.Write(e.Encode(VersionJSON, OpMessage, body))
+	}
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch version {
+	case VersionZlib:
+		w = zlib.NewWriter(&buf)
+	case VersionBrotli:
+		w = brotli.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("packet: unsupported compress version %d", version)
+	}
+	if _, err := w.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return e.Encode(version, OpMessage, buf.Bytes()), nil
+}
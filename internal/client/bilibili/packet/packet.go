@@ -0,0 +1,50 @@
+// Package packet 实现 bilibili 直播弹幕 WebSocket 的数据包编解码.
+//
+// 数据包由 16 字节大端序头部与消息体组成:
+//
+//	0       4        6         8    12        16
+//	| 总长度 | 头部长度 | 协议版本 | 操作码 | 序列号 | 消息体 ...
+package packet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HeaderLen 数据包头部长度
+const HeaderLen = 16
+
+// 协议版本
+const (
+	VersionJSON   uint16 = 0 // 未压缩的 JSON 消息
+	VersionInt32  uint16 = 1 // 心跳回复与鉴权回复等
+	VersionZlib   uint16 = 2 // zlib 压缩的数据包集合
+	VersionBrotli uint16 = 3 // brotli 压缩的数据包集合
+)
+
+// 操作码
+const (
+	OpHeartbeat      uint32 = 2 // 心跳
+	OpHeartbeatReply uint32 = 3 // 心跳回复, 消息体为 4 字节人气值
+	OpMessage        uint32 = 5 // 业务消息
+	OpAuth           uint32 = 7 // 鉴权
+	OpAuthReply      uint32 = 8 // 鉴权回复
+)
+
+var (
+	ErrShortPacket   = errors.New("packet: short packet")
+	ErrInvalidLength = errors.New("packet: invalid packet length")
+	ErrInvalidHeader = errors.New("packet: invalid header length")
+	ErrBodyTooLarge  = errors.New("packet: decompressed body too large")
+)
+
+type Packet struct {
+	Version  uint16
+	Op       uint32
+	Sequence uint32
+	Body     []byte
+}
+
+func (p Packet) String() string {
+	return fmt.Sprintf("Packet{Version: %d, Op: %d, Sequence: %d, BodyLen: %d}", p.Version, p.Op, p.Sequence, len(p.Body))
+}
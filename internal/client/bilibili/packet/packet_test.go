@@ -0,0 +1,290 @@
+package packet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	versions := []uint16{VersionJSON, VersionInt32, VersionZlib, VersionBrotli}
+	ops := []uint32{OpHeartbeat, OpHeartbeatReply, OpMessage, OpAuth, OpAuthReply}
+	bodies := [][]byte{nil, {0, 0, 4, 0}, []byte(`{"cmd":"DANMU_MSG"}`)}
+
+	for _, version := range versions {
+		for _, op := range ops {
+			for _, body := range bodies {
+				want := Packet{Version: version, Op: op, Sequence: 7, Body: body}
+				buf := Marshal(want)
+				if len(buf) != HeaderLen+len(body) {
+					t.Fatalf("Marshal(%v) len = %d, want %d", want, len(buf), HeaderLen+len(body))
+				}
+
+				got, n, err := Unmarshal(buf)
+				if err != nil {
+					t.Fatalf("Unmarshal(%v) err: %v", want, err)
+				}
+				if n != len(buf) {
+					t.Errorf("Unmarshal(%v) n = %d, want %d", want, n, len(buf))
+				}
+				if got.Version != want.Version || got.Op != want.Op || got.Sequence != want.Sequence || !bytes.Equal(got.Body, want.Body) {
+					t.Errorf("Unmarshal(Marshal(%v)) = %v", want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestEncoderSequence(t *testing.T) {
+	e := NewEncoder()
+	for want := uint32(0); want < 3; want++ {
+		p, _, err := Unmarshal(e.Encode(VersionInt32, OpHeartbeat, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Sequence != want {
+			t.Errorf("Sequence = %d, want %d", p.Sequence, want)
+		}
+	}
+}
+
+// header 构造头部, 用于生成非法数据包
+func header(total uint32, headerLen uint16) []byte {
+	buf := make([]byte, HeaderLen)
+	binary.BigEndian.PutUint32(buf[0:4], total)
+	binary.BigEndian.PutUint16(buf[4:6], headerLen)
+	binary.BigEndian.PutUint32(buf[8:12], OpMessage)
+	return buf
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	valid := Marshal(Packet{Op: OpMessage, Body: []byte("{}")})
+
+	tests := []struct {
+		name string
+		src  []byte
+		err  error
+	}{
+		{"empty", nil, ErrShortPacket},
+		{"short header", valid[:HeaderLen-1], ErrShortPacket},
+		{"truncated body", valid[:len(valid)-1], ErrInvalidLength},
+		{"total larger than buffer", append(header(1024, HeaderLen), "{}"...), ErrInvalidLength},
+		{"header shorter than 16", append(header(18, 8), "{}"...), ErrInvalidHeader},
+		{"header larger than total", append(header(18, 32), "{}"...), ErrInvalidHeader},
+		{"total smaller than header", header(4, HeaderLen), ErrInvalidHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Unmarshal(tt.src); !errors.Is(err, tt.err) {
+				t.Errorf("Unmarshal err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalLongHeader(t *testing.T) {
+	// 头部长度大于 16 时跳过多余的头部
+	src := append(header(22, 20), "xxxx{}"...)
+	p, n, err := Unmarshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 22 || string(p.Body) != "{}" {
+		t.Errorf("Unmarshal = %v, %d, want body {} and n 22", p, n)
+	}
+}
+
+// compress 将 data 压缩后封装为一个数据包
+func compress(t testing.TB, version uint16, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch version {
+	case VersionZlib:
+		w := zlib.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+	case VersionBrotli:
+		w := brotli.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+	}
+	return Marshal(Packet{Version: version, Op: OpMessage, Body: buf.Bytes()})
+}
+
+// batch 将多条业务消息合并压缩为一个数据包, 与服务端推送的格式相同
+func batch(t testing.TB, version uint16, bodies ...[]byte) []byte {
+	t.Helper()
+	var buf []byte
+	for _, body := range bodies {
+		buf = append(buf, Marshal(Packet{Version: VersionJSON, Op: OpMessage, Body: body})...)
+	}
+	return compress(t, version, buf)
+}
+
+func TestDecode(t *testing.T) {
+	var (
+		bodies      = [][]byte{[]byte(`{"cmd":"A"}`), []byte(`{"cmd":"B"}`)}
+		plain       = Marshal(Packet{Version: VersionInt32, Op: OpHeartbeatReply, Body: []byte{0, 0, 1, 0}})
+		zlibFrame   = batch(t, VersionZlib, bodies...)
+		brotliFrame = batch(t, VersionBrotli, bodies...)
+	)
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  []string
+	}{
+		{"plain", plain, []string{"\x00\x00\x01\x00"}},
+		{"zlib", zlibFrame, []string{`{"cmd":"A"}`, `{"cmd":"B"}`}},
+		{"brotli", brotliFrame, []string{`{"cmd":"A"}`, `{"cmd":"B"}`}},
+		{"brotli in zlib", compress(t, VersionZlib, brotliFrame), []string{`{"cmd":"A"}`, `{"cmd":"B"}`}},
+		{"zlib in brotli", compress(t, VersionBrotli, zlibFrame), []string{`{"cmd":"A"}`, `{"cmd":"B"}`}},
+		{"mixed", bytes.Join([][]byte{plain, brotliFrame, zlibFrame}, nil),
+			[]string{"\x00\x00\x01\x00", `{"cmd":"A"}`, `{"cmd":"B"}`, `{"cmd":"A"}`, `{"cmd":"B"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkts, err := NewDecoder().Decode(tt.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pkts) != len(tt.want) {
+				t.Fatalf("Decode got %d packets, want %d", len(pkts), len(tt.want))
+			}
+			for i, p := range pkts {
+				if p.Version == VersionZlib || p.Version == VersionBrotli {
+					t.Errorf("packet %d is still compressed: %v", i, p)
+				}
+				if string(p.Body) != tt.want[i] {
+					t.Errorf("packet %d body = %q, want %q", i, p.Body, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	var (
+		plain       = Marshal(Packet{Op: OpMessage, Body: []byte("{}")})
+		brotliFrame = batch(t, VersionBrotli, []byte("{}"))
+	)
+
+	tests := []struct {
+		name  string
+		frame []byte
+		n     int // 出错前已解码的数据包数
+	}{
+		{"corrupt zlib", append(plain, Marshal(Packet{Version: VersionZlib, Op: OpMessage, Body: []byte("not zlib")})...), 1},
+		{"corrupt brotli", append(plain, Marshal(Packet{Version: VersionBrotli, Op: OpMessage, Body: []byte("not brotli")})...), 1},
+		{"truncated brotli", brotliFrame[:len(brotliFrame)-2], 0},
+		{"trailing garbage", append(plain, 0, 1, 2), 1},
+		{"compressed garbage", compress(t, VersionZlib, []byte("garbage")), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkts, err := NewDecoder().Decode(tt.frame)
+			if err == nil {
+				t.Fatal("Decode err = nil, want error")
+			}
+			if len(pkts) != tt.n {
+				t.Errorf("Decode got %d packets before error, want %d", len(pkts), tt.n)
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var (
+		body  = bytes.Repeat([]byte("a"), 1000)
+		frame = batch(t, VersionZlib, body)
+		size  = HeaderLen + len(body)
+	)
+
+	tests := []struct {
+		limit int
+		err   error
+	}{
+		{size - 1, ErrBodyTooLarge},
+		{size, nil},
+		{0, nil}, // 使用 DefaultMaxBodySize
+	}
+	for _, tt := range tests {
+		d := &Decoder{MaxBodySize: tt.limit}
+		if _, err := d.Decode(frame); !errors.Is(err, tt.err) {
+			t.Errorf("MaxBodySize %d: err = %v, want %v", tt.limit, err, tt.err)
+		}
+	}
+}
+
+// capturedFrames 从弹幕服务录制的原始 WebSocket 帧
+func capturedFrames(t testing.TB) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		frame, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames[filepath.Base(path)] = frame
+	}
+	return frames
+}
+
+func TestDecodeCaptured(t *testing.T) {
+	for name, frame := range capturedFrames(t) {
+		t.Run(name, func(t *testing.T) {
+			pkts, err := NewDecoder().Decode(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pkts) == 0 {
+				t.Fatal("Decode got no packets")
+			}
+			for i, p := range pkts {
+				if p.Version != VersionJSON || p.Op != OpMessage || !bytes.HasPrefix(p.Body, []byte("{")) {
+					t.Errorf("packet %d = %v %q, want JSON message", i, p, p.Body)
+				}
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, frame := range capturedFrames(f) {
+		f.Add(frame)
+	}
+	f.Add(Marshal(Packet{Version: VersionInt32, Op: OpHeartbeatReply, Body: []byte{0, 0, 4, 0}}))
+	f.Add(Marshal(Packet{Version: VersionJSON, Op: OpAuthReply, Body: []byte(`{"code":0}`)}))
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		d := &Decoder{MaxBodySize: 1 << 20}
+		pkts, _ := d.Decode(frame)
+		for _, p := range pkts {
+			if p.Version == VersionZlib || p.Version == VersionBrotli {
+				t.Fatalf("Decode returned compressed packet %v", p)
+			}
+		}
+
+		p, n, err := Unmarshal(frame)
+		if err != nil {
+			return
+		}
+		if n < HeaderLen || n > len(frame) {
+			t.Fatalf("Unmarshal n = %d, frame len %d", n, len(frame))
+		}
+		// 标准头部长度的数据包重新编码后应与原数据一致
+		if binary.BigEndian.Uint16(frame[4:6]) == HeaderLen && !bytes.Equal(Marshal(p), frame[:n]) {
+			t.Fatalf("Marshal(Unmarshal(frame)) differs from frame")
+		}
+	})
+}
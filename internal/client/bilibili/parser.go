@@ -0,0 +1,202 @@
+package bilibili
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// unixOrZero 将秒级时间戳转换为 time.Time, 不存在时返回零值, 由 dispatchFrame 填入帧的接收时间
+func unixOrZero(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+func parseDanmaku(body gjson.Result) (client.Message, error) {
+	dmk := &Danmaku{
+		UID:     body.Get("info.2.0").Int(),
+		Author:  body.Get("info.2.1").String(),
+		Content: strings.ReplaceAll(body.Get("info.1").String(), "\r", ""),
+	}
+	if ts := body.Get("info.0.4").Int(); ts > 0 {
+		dmk.T = time.UnixMilli(ts)
+	}
+	if body.Get("info.0.12").Int() == 1 {
+		dmk.Emoticon = body.Get("info.0.13.emoticon_unique").String()
+	}
+	if medal := body.Get("info.0.15.user.medal"); medal.IsObject() {
+		dmk.Medal = &Medal{
+			Level: int(medal.Get("level").Int()),
+			Name:  medal.Get("name").String(),
+		}
+	}
+	return client.Message{Type: client.BiliBiliDanmaku, Data: dmk}, nil
+}
+
+func parseSuperChat(body gjson.Result) (client.Message, error) {
+	data := body.Get("data")
+	return client.Message{
+		Type: client.BiliBiliSuperChat,
+		Data: &SuperChat{
+			ID:       data.Get("id").Int(),
+			UID:      data.Get("uid").Int(),
+			Author:   data.Get("user_info.uname").String(),
+			Content:  strings.ReplaceAll(data.Get("message").String(), "\r", ""),
+			Price:    data.Get("price").Int(),
+			Duration: time.Duration(data.Get("time").Int()) * time.Second,
+			T:        unixOrZero(data.Get("start_time").Int()),
+		},
+	}, nil
+}
+
+func parseGift(body gjson.Result) (client.Message, error) {
+	data := body.Get("data")
+	return client.Message{
+		Type: client.BiliBiliGift,
+		Data: &Gift{
+			UID:       data.Get("uid").Int(),
+			Author:    data.Get("uname").String(),
+			Action:    data.Get("action").String(),
+			GiftID:    data.Get("giftId").Int(),
+			GiftName:  data.Get("giftName").String(),
+			Num:       data.Get("num").Int(),
+			Price:     data.Get("price").Int(),
+			TotalCoin: data.Get("total_coin").Int(),
+			CoinType:  data.Get("coin_type").String(),
+			T:         unixOrZero(data.Get("timestamp").Int()),
+		},
+	}, nil
+}
+
+func parseComboGift(body gjson.Result) (client.Message, error) {
+	var (
+		data      = body.Get("data")
+		num       = data.Get("combo_num").Int()
+		totalCoin = data.Get("combo_total_coin").Int()
+		gift      = &Gift{
+			UID:       data.Get("uid").Int(),
+			Author:    data.Get("uname").String(),
+			Action:    data.Get("action").String(),
+			GiftID:    data.Get("gift_id").Int(),
+			GiftName:  data.Get("gift_name").String(),
+			Num:       num,
+			TotalCoin: totalCoin,
+			CoinType:  data.Get("coin_type").String(),
+			Combo:     true,
+		}
+	)
+	if num > 0 {
+		gift.Price = totalCoin / num
+	}
+	return client.Message{Type: client.BiliBiliGift, Data: gift}, nil
+}
+
+func parseGuardBuy(body gjson.Result) (client.Message, error) {
+	data := body.Get("data")
+	return client.Message{
+		Type: client.BiliBiliGuardPurchase,
+		Data: &GuardPurchase{
+			UID:        data.Get("uid").Int(),
+			Author:     data.Get("username").String(),
+			GuardLevel: int(data.Get("guard_level").Int()),
+			GiftID:     data.Get("gift_id").Int(),
+			GiftName:   data.Get("gift_name").String(),
+			Num:        data.Get("num").Int(),
+			Price:      data.Get("price").Int(),
+			T:          unixOrZero(data.Get("start_time").Int()),
+		},
+	}, nil
+}
+
+func parseInteractWord(body gjson.Result) (client.Message, error) {
+	data := body.Get("data")
+	return client.Message{
+		Type: client.BiliBiliUserEnter,
+		Data: &UserEnter{
+			UID:    data.Get("uid").Int(),
+			Author: data.Get("uname").String(),
+			Type:   InteractType(data.Get("msg_type").Int()),
+			T:      unixOrZero(data.Get("timestamp").Int()),
+		},
+	}, nil
+}
+
+func parseInteractWordV2(body gjson.Result) (client.Message, error) {
+	// NOTE: 将data.pb 使用如下命令进行解析
+	// echo "$data.pb" | base64 -d | protoc --decode_raw
+	data, err := base64.StdEncoding.DecodeString(body.Get("data.pb").String())
+	if err != nil {
+		return client.Message{}, fmt.Errorf("base64 decode INTERACT_WORD_V2 err: %v", err)
+	}
+
+	enter := &UserEnter{Type: InteractEnter}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			break
+		}
+		data = data[n:]
+
+		// 1: uid, 2: uname, 5: msg_type, 7: timestamp
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(data)
+			enter.UID = int64(v)
+		case num == 2 && typ == protowire.BytesType:
+			enter.Author, _ = protowire.ConsumeString(data)
+		case num == 5 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(data)
+			enter.Type = InteractType(v)
+		case num == 7 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(data)
+			enter.T = unixOrZero(int64(v))
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			break
+		}
+		data = data[n:]
+	}
+	return client.Message{Type: client.BiliBiliUserEnter, Data: enter}, nil
+}
+
+func parseWatchedChange(body gjson.Result) (client.Message, error) {
+	return client.Message{
+		Type: client.BiliBiliStatsUpdate,
+		Data: &StatsUpdate{
+			Kind:  StatsWatched,
+			Value: body.Get("data.num").Int(),
+			Text:  body.Get("data.text_large").String(),
+		},
+	}, nil
+}
+
+func parseLikeInfo(body gjson.Result) (client.Message, error) {
+	return client.Message{
+		Type: client.BiliBiliStatsUpdate,
+		Data: &StatsUpdate{
+			Kind:  StatsLiked,
+			Value: body.Get("data.click_count").Int(),
+			Text:  body.Get("data.click_count").String(),
+		},
+	}, nil
+}
+
+func parseOnlineRankCount(body gjson.Result) (client.Message, error) {
+	return client.Message{
+		Type: client.BiliBiliStatsUpdate,
+		Data: &StatsUpdate{
+			Kind:  StatsOnline,
+			Value: body.Get("data.count").Int(),
+			Text:  body.Get("data.online_count_text").String(),
+		},
+	}, nil
+}
@@ -0,0 +1,196 @@
+package bilibili
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/tidwall/gjson"
+)
+
+// loadFixture 读取 testdata/cmd 下的业务消息
+func loadFixture(t *testing.T, name string) gjson.Result {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "cmd", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gjson.ValidBytes(data) {
+		t.Fatalf("fixture %s is not valid JSON", name)
+	}
+	return gjson.ParseBytes(data)
+}
+
+// clearBody 清除未解析命令的原始消息体, 以便比较
+func clearBody(data any) any {
+	if v, ok := data.(*RawEvent); ok {
+		v.Body = gjson.Result{}
+	}
+	return data
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		fixture string
+		typ     client.MessageType
+		want    any
+	}{
+		{"DANMU_MSG", client.BiliBiliDanmaku, &Danmaku{
+			UID:     12345,
+			Author:  "弹幕用户",
+			Content: "晚上好",
+			Medal:   &Medal{Name: "粉丝团", Level: 12},
+			T:       time.UnixMilli(1700000000123),
+		}},
+		{"DANMU_MSG_emoticon", client.BiliBiliDanmaku, &Danmaku{
+			UID:      23456,
+			Author:   "表情用户",
+			Content:  "赞",
+			Emoticon: "room_123_4567",
+			T:        time.UnixMilli(1700000000456),
+		}},
+		{"SEND_GIFT", client.BiliBiliGift, &Gift{
+			UID:       34567,
+			Author:    "送礼用户",
+			Action:    "投喂",
+			GiftID:    31036,
+			GiftName:  "小花花",
+			Num:       3,
+			Price:     100,
+			TotalCoin: 300,
+			CoinType:  "gold",
+			T:         time.Unix(1700000001, 0),
+		}},
+		{"COMBO_SEND", client.BiliBiliGift, &Gift{
+			UID:      45678,
+			Author:   "连击用户",
+			Action:   "投喂",
+			GiftID:   1,
+			GiftName: "辣条",
+			Num:      10,
+			CoinType: "silver",
+			Combo:    true,
+		}},
+		{"SUPER_CHAT_MESSAGE", client.BiliBiliSuperChat, &SuperChat{
+			ID:       8765432,
+			UID:      56789,
+			Author:   "留言用户",
+			Content:  "主播加油",
+			Price:    30,
+			Duration: time.Minute,
+			T:        time.Unix(1700000002, 0),
+		}},
+		{"GUARD_BUY", client.BiliBiliGuardPurchase, &GuardPurchase{
+			UID:        67890,
+			Author:     "上舰用户",
+			GuardLevel: 3,
+			GiftID:     10003,
+			GiftName:   "舰长",
+			Num:        1,
+			Price:      198000,
+			T:          time.Unix(1700000003, 0),
+		}},
+		{"INTERACT_WORD", client.BiliBiliUserEnter, &UserEnter{
+			UID:    78901,
+			Author: "进场用户",
+			Type:   InteractEnter,
+			T:      time.Unix(1700000004, 0),
+		}},
+		{"INTERACT_WORD_V2", client.BiliBiliUserEnter, &UserEnter{
+			UID:    35879,
+			Author: "关注的观众",
+			Type:   InteractFollow,
+			T:      time.Unix(1700000000, 0),
+		}},
+		{"WATCHED_CHANGE", client.BiliBiliStatsUpdate, &StatsUpdate{
+			Kind:  StatsWatched,
+			Value: 12345,
+			Text:  "1.2万人看过",
+		}},
+		{"LIVE", client.BiliBiliRaw, &RawEvent{Cmd: "LIVE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			msg, ok, err := DefaultRegistry.Parse(loadFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("Parse ok = false")
+			}
+			if msg.Type != tt.typ {
+				t.Errorf("Type = %v, want %v", msg.Type, tt.typ)
+			}
+			if got := clearBody(msg.Data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Data = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInteractWordV2Invalid(t *testing.T) {
+	body := gjson.Parse(`{"cmd":"INTERACT_WORD_V2","data":{"pb":"not base64!"}}`)
+	if _, _, err := DefaultRegistry.Parse(body); err == nil {
+		t.Error("Parse err = nil, want error for invalid base64")
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	tests := []struct {
+		cmd string
+		ok  bool
+	}{
+		{"DANMU_MSG", true},
+		{"DANMU_MSG:4:0:2:2:2:0", true},
+		{"SUPER_CHAT_MESSAGE_JPN", true},
+		{"INTERACT_WORD_V2", true},
+		{"DANMU", false},
+		{"UNKNOWN_CMD", false},
+		{"UNKNOWN_CMD:1:2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := DefaultRegistry.Lookup(tt.cmd); ok != tt.ok {
+			t.Errorf("Lookup(%q) ok = %v, want %v", tt.cmd, ok, tt.ok)
+		}
+	}
+}
+
+func TestRegistryFallback(t *testing.T) {
+	body := gjson.Parse(`{"cmd":"UNKNOWN_CMD","data":{}}`)
+
+	// 默认注册表将未注册的命令原样转发
+	msg, ok, err := DefaultRegistry.Parse(body)
+	if err != nil || !ok || msg.Type != client.BiliBiliRaw {
+		t.Fatalf("Parse = %v, %v, %v, want raw message", msg, ok, err)
+	}
+	if v := msg.Data.(*RawEvent); v.Cmd != "UNKNOWN_CMD" {
+		t.Errorf("Cmd = %q, want UNKNOWN_CMD", v.Cmd)
+	}
+
+	// 没有 fallback 时丢弃
+	r := DefaultRegistry.Clone()
+	r.SetFallback(nil)
+	if _, ok, _ := r.Parse(body); ok {
+		t.Error("Parse ok = true without fallback")
+	}
+
+	// 注册后覆盖 fallback, 且不影响 DefaultRegistry
+	r.Register("UNKNOWN_CMD", func(gjson.Result) (client.Message, error) {
+		return client.Message{Type: client.Unknown}, nil
+	})
+	if msg, ok, _ := r.Parse(body); !ok || msg.Type != client.Unknown {
+		t.Errorf("Parse after Register = %v, %v", msg, ok)
+	}
+	if _, ok := DefaultRegistry.Lookup("UNKNOWN_CMD"); ok {
+		t.Error("Register on clone changed DefaultRegistry")
+	}
+	r.Unregister("DANMU_MSG")
+	if _, ok := r.Lookup("DANMU_MSG:4:0:2:2:2:0"); ok {
+		t.Error("Lookup found unregistered DANMU_MSG")
+	}
+}
@@ -0,0 +1,186 @@
+package bilibili
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
+
+// OverflowPolicy 消息队列写满时的处理方式
+type OverflowPolicy int
+
+const (
+	OverflowBlock           OverflowPolicy = iota // 等待使用方读取, 会阻塞弹幕连接的读取
+	OverflowDropOldest                            // 丢弃最早的可丢弃消息
+	OverflowDropLowPriority                       // 先丢弃进房、统计等低优先级消息, 再丢弃最早的弹幕
+)
+
+// ParseOverflowPolicy 解析配置中的策略名称: block, drop_oldest, drop_low_priority
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "block":
+		return OverflowBlock, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	case "drop_low_priority":
+		return OverflowDropLowPriority, nil
+	default:
+		return OverflowBlock, fmt.Errorf("unknown overflow policy %q", s)
+	}
+}
+
+// DropStats 因队列写满被丢弃的消息数
+type DropStats struct {
+	Total  uint64
+	ByType map[client.MessageType]uint64
+}
+
+type priority int
+
+const (
+	priorityLow    priority = iota // 进房、统计、人气、未解析命令
+	priorityNormal                 // 弹幕、房间信息、打榜
+	priorityKeep                   // 醒目留言、礼物、上舰、连接状态、丢弃统计与发送结果, 从不丢弃
+)
+
+func priorityOf(msg client.Message) priority {
+	switch msg.Type {
+	case client.BiliBiliSuperChat, client.BiliBiliGift, client.BiliBiliGuardPurchase,
+		client.BiliBiliConnState, client.BiliBiliDropped, client.BiliBiliSendResult, client.BiliBiliSendEcho:
+		return priorityKeep
+	case client.BiliBiliUserEnter, client.BiliBiliStatsUpdate, client.BiliBiliPopularity, client.BiliBiliRaw:
+		return priorityLow
+	default:
+		return priorityNormal
+	}
+}
+
+// queue 客户端与使用方之间的有界消息队列, 写满时按 policy 处理新消息,
+// 不可丢弃的消息在非阻塞策略下允许超出容量
+type queue struct {
+	policy OverflowPolicy
+	size   int
+	out    chan client.Message
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	items       []client.Message
+	closed      bool
+	stats       DropStats
+	statsQueued bool // 队列中已有一条丢弃统计, 发送时再填入最新的计数
+}
+
+func newQueue(policy OverflowPolicy, size int) *queue {
+	q := &queue{
+		policy: policy,
+		size:   size,
+		out:    make(chan client.Message, 64),
+		stats:  DropStats{ByType: make(map[client.MessageType]uint64)},
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push 加入一条消息, 只有 OverflowBlock 策略会阻塞
+func (q *queue) Push(msg client.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) >= q.size {
+		switch {
+		case q.policy == OverflowBlock:
+			for len(q.items) >= q.size && !q.closed {
+				q.cond.Wait()
+			}
+		case priorityOf(msg) == priorityKeep:
+		case q.policy == OverflowDropOldest:
+			if !q.dropFirst(priorityNormal) {
+				q.drop(msg)
+				return
+			}
+		case q.policy == OverflowDropLowPriority:
+			if q.dropFirst(priorityLow) {
+				break
+			}
+			if priorityOf(msg) == priorityLow || !q.dropFirst(priorityNormal) {
+				q.drop(msg)
+				return
+			}
+		}
+	}
+	if q.closed {
+		return
+	}
+	q.items = append(q.items, msg)
+	q.cond.Broadcast()
+}
+
+// dropFirst 丢弃最早的一条优先级不高于 p 的消息
+func (q *queue) dropFirst(p priority) bool {
+	for i, msg := range q.items {
+		if priorityOf(msg) <= p {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.drop(msg)
+			return true
+		}
+	}
+	return false
+}
+
+// drop 记录被丢弃的消息, 并排入一条丢弃统计通知使用方
+func (q *queue) drop(msg client.Message) {
+	q.stats.Total++
+	q.stats.ByType[msg.Type]++
+	if !q.statsQueued {
+		q.statsQueued = true
+		q.items = append(q.items, client.Message{Type: client.BiliBiliDropped})
+	}
+}
+
+// Stats 当前的丢弃统计
+func (q *queue) Stats() DropStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return DropStats{Total: q.stats.Total, ByType: maps.Clone(q.stats.ByType)}
+}
+
+// run 将队列中的消息依次发送到 out, ctx 结束后关闭 out, 未发送的消息被丢弃
+func (q *queue) run(ctx context.Context) {
+	defer close(q.out)
+	go func() {
+		<-ctx.Done()
+		q.mu.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}()
+
+	for {
+		q.mu.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		msg := q.items[0]
+		q.items[0] = client.Message{}
+		q.items = q.items[1:]
+		if msg.Type == client.BiliBiliDropped {
+			q.statsQueued = false
+			msg.Data = &DropStats{Total: q.stats.Total, ByType: maps.Clone(q.stats.ByType)}
+		}
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		select {
+		case q.out <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package bilibili
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
+
+func TestQueueCloseOut(t *testing.T) {
+	q := newQueue(OverflowBlock, 4)
+	ctx, cf := context.WithCancel(context.Background())
+	go q.run(ctx)
+
+	q.Push(client.Message{Type: client.BiliBiliDanmaku})
+	select {
+	case msg := <-q.out:
+		if msg.Type != client.BiliBiliDanmaku {
+			t.Errorf("Type = %v, want danmaku", msg.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+
+	// ctx 结束后 out 关闭, 使用方的 range 循环可以退出
+	cf()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-q.out:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("out is not closed after ctx is done")
+		}
+	}
+}
+
+func TestClientReceiveClosed(t *testing.T) {
+	_, c := startMock(t)
+	waitFor(t, c, "danmaku", isType(client.BiliBiliDanmaku))
+
+	c.Stop()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case _, ok := <-c.Receive():
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Receive channel is not closed after Stop")
+		}
+	}
+}
+
+// item 测试用消息, Data 为编号, 以便区分同类型的消息
+type item struct {
+	typ client.MessageType
+	id  int
+}
+
+func TestQueueOverflow(t *testing.T) {
+	var (
+		danmaku = func(id int) item { return item{client.BiliBiliDanmaku, id} }
+		enter   = func(id int) item { return item{client.BiliBiliUserEnter, id} }
+		gift    = func(id int) item { return item{client.BiliBiliGift, id} }
+		dropped = item{client.BiliBiliDropped, 0}
+	)
+
+	tests := []struct {
+		name    string
+		policy  OverflowPolicy
+		push    []item
+		want    []item
+		dropped map[client.MessageType]uint64
+	}{
+		{
+			name:   "drop oldest",
+			policy: OverflowDropOldest,
+			push:   []item{danmaku(1), enter(1), danmaku(2), danmaku(3), gift(1), danmaku(4)},
+			// 写满后丢弃最早的可丢弃消息, 礼物允许超出容量, 丢弃统计只排入一条
+			want:    []item{danmaku(2), dropped, danmaku(3), gift(1), danmaku(4)},
+			dropped: map[client.MessageType]uint64{client.BiliBiliDanmaku: 1, client.BiliBiliUserEnter: 1},
+		},
+		{
+			name:   "drop oldest keeps gifts",
+			policy: OverflowDropOldest,
+			push:   []item{gift(1), gift(2), gift(3), danmaku(1), gift(4)},
+			// 队列中没有可丢弃的消息时丢弃新的弹幕
+			want:    []item{gift(1), gift(2), gift(3), dropped, gift(4)},
+			dropped: map[client.MessageType]uint64{client.BiliBiliDanmaku: 1},
+		},
+		{
+			name:   "drop low priority",
+			policy: OverflowDropLowPriority,
+			push:   []item{danmaku(1), enter(1), danmaku(2), danmaku(3), enter(2), danmaku(4), gift(1)},
+			// 先丢弃队列中的进房消息, 没有低优先级消息时新的进房消息直接丢弃, 再丢弃最早的弹幕
+			want:    []item{danmaku(2), dropped, danmaku(3), danmaku(4), gift(1)},
+			dropped: map[client.MessageType]uint64{client.BiliBiliDanmaku: 1, client.BiliBiliUserEnter: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue(tt.policy, 3)
+			for _, it := range tt.push {
+				q.Push(client.Message{Type: it.typ, Data: it.id})
+			}
+
+			var got []item
+			for _, msg := range q.items {
+				id, _ := msg.Data.(int)
+				got = append(got, item{msg.Type, id})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+
+			var total uint64
+			for _, n := range tt.dropped {
+				total += n
+			}
+			if stats := q.Stats(); stats.Total != total || !reflect.DeepEqual(stats.ByType, tt.dropped) {
+				t.Errorf("Stats = %+v, want total %d by type %v", stats, total, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestQueueOverflowBlock(t *testing.T) {
+	q := newQueue(OverflowBlock, 2)
+	for i := range 2 {
+		q.Push(client.Message{Type: client.BiliBiliUserEnter, Data: i})
+	}
+
+	// 写满后 Push 等待使用方读取, 不丢弃任何消息
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(client.Message{Type: client.BiliBiliUserEnter, Data: 2})
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push returned while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	go q.run(ctx)
+	for want := range 3 {
+		select {
+		case msg := <-q.out:
+			if msg.Data != want {
+				t.Errorf("message %d Data = %v", want, msg.Data)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for message %d", want)
+		}
+	}
+	<-pushed
+	if stats := q.Stats(); stats.Total != 0 {
+		t.Errorf("Stats.Total = %d, want 0", stats.Total)
+	}
+}
@@ -0,0 +1,25 @@
+package bilibili
+
+import (
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/tidwall/gjson"
+)
+
+// RawEvent 未注册解析函数的命令, 保留原始消息体
+type RawEvent struct {
+	Cmd  string
+	Body gjson.Result
+	T    time.Time
+}
+
+func parseRaw(body gjson.Result) (client.Message, error) {
+	return client.Message{
+		Type: client.BiliBiliRaw,
+		Data: &RawEvent{
+			Cmd:  body.Get("cmd").String(),
+			Body: body,
+		},
+	}, nil
+}
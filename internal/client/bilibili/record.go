@@ -0,0 +1,120 @@
+package bilibili
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+)
+
+// recordMagic 录制文件头
+//
+// 文件头之后的每条记录为: 8 字节接收时间 (UnixNano) | 4 字节帧长度 | 原始 WebSocket 帧, 均为大端序
+const recordMagic = "BILICHAT-REC\x01"
+
+// maxRecordFrame 单帧长度上限, 避免损坏的录制文件导致超大内存分配
+const maxRecordFrame = packet.DefaultMaxBodySize + packet.HeaderLen
+
+var ErrInvalidRecord = errors.New("invalid record file")
+
+// Recorder 将接收到的原始 WebSocket 帧及其接收时间写入同一个文件, 可并发使用
+type Recorder struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+	c  io.Closer
+}
+
+// CreateRecorder 创建录制文件, 已存在时覆盖
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRecorder(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.c = f
+	return r, nil
+}
+
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{w: bufio.NewWriter(w)}
+	if _, err := r.w.WriteString(recordMagic); err != nil {
+		return nil, err
+	}
+	return r, r.w.Flush()
+}
+
+// Write 写入一帧, 每帧写入后立即落盘, 避免异常退出时丢失
+func (r *Recorder) Write(t time.Time, frame []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var head [12]byte
+	binary.BigEndian.PutUint64(head[0:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint32(head[8:12], uint32(len(frame)))
+	if _, err := r.w.Write(head[:]); err != nil {
+		return err
+	}
+	if _, err := r.w.Write(frame); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	if r.c != nil {
+		return r.c.Close()
+	}
+	return nil
+}
+
+// RecordReader 按顺序读取录制文件中的帧
+type RecordReader struct {
+	r *bufio.Reader
+}
+
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != recordMagic {
+		return nil, ErrInvalidRecord
+	}
+	return &RecordReader{r: br}, nil
+}
+
+// Next 读取下一帧, 读完时返回 io.EOF
+func (r *RecordReader) Next() (t time.Time, frame []byte, err error) {
+	var head [12]byte
+	if _, err = io.ReadFull(r.r, head[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("%w: truncated record header", ErrInvalidRecord)
+		}
+		return t, nil, err
+	}
+
+	t = time.Unix(0, int64(binary.BigEndian.Uint64(head[0:8])))
+	n := binary.BigEndian.Uint32(head[8:12])
+	if n > maxRecordFrame {
+		return t, nil, fmt.Errorf("%w: frame length %d exceeds %d", ErrInvalidRecord, n, maxRecordFrame)
+	}
+	frame = make([]byte, n)
+	if _, err = io.ReadFull(r.r, frame); err != nil {
+		return t, nil, fmt.Errorf("%w: truncated frame", ErrInvalidRecord)
+	}
+	return t, frame, nil
+}
@@ -0,0 +1,82 @@
+package bilibili
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
+
+// fromFrame 由弹幕连接推送的消息, 回放时应原样重现
+func fromFrame(msg client.Message) bool {
+	switch msg.Type {
+	case client.BiliBiliDanmaku, client.BiliBiliSuperChat, client.BiliBiliGift, client.BiliBiliGuardPurchase,
+		client.BiliBiliUserEnter, client.BiliBiliStatsUpdate, client.BiliBiliRaw, client.BiliBiliPopularity:
+		return true
+	}
+	return false
+}
+
+// wallTime 去除事件时间中的单调时钟读数, 录制文件只保存墙上时间
+func wallTime(data any) any {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Pointer {
+		if f := v.Elem().FieldByName("T"); f.IsValid() && f.CanSet() {
+			f.Set(reflect.ValueOf(f.Interface().(time.Time).Round(0)))
+		}
+	}
+	return data
+}
+
+func TestRecordReplay(t *testing.T) {
+	const n = 20
+	path := filepath.Join(t.TempDir(), "rec.bin")
+	recorder, err := CreateRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, c := startMock(t, WithRecorder(recorder))
+
+	var live []client.Message
+	for len(live) < n {
+		live = append(live, waitFor(t, c, "live event", fromFrame))
+	}
+	c.Stop()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := NewReplayClient(path, 0)
+	if err := rc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Stop()
+
+	var replayed []client.Message
+	timeout := time.After(waitTimeout)
+	for len(replayed) < n {
+		select {
+		case msg, ok := <-rc.Receive():
+			if !ok {
+				t.Fatalf("replay ended after %d events, want %d", len(replayed), n)
+			}
+			if fromFrame(msg) {
+				replayed = append(replayed, msg)
+			}
+		case <-timeout:
+			t.Fatal("timeout waiting for replayed events")
+		}
+	}
+
+	// 回放的事件顺序、内容与时间均与录制时一致
+	for i := range live {
+		if live[i].Type != replayed[i].Type {
+			t.Fatalf("event %d Type = %v, want %v", i, replayed[i].Type, live[i].Type)
+		}
+		if got, want := clearBody(replayed[i].Data), wallTime(clearBody(live[i].Data)); !reflect.DeepEqual(got, want) {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package bilibili
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/tidwall/gjson"
+)
+
+// CommandParser 将一条业务消息解析为 client.Message, body 为完整的消息 JSON.
+// 消息体中没有时间时内置事件的 T 保持零值, 由客户端填入帧的接收时间
+type CommandParser func(body gjson.Result) (client.Message, error)
+
+// Registry 维护命令名到解析函数的映射, 可并发使用
+type Registry struct {
+	mu       sync.RWMutex
+	parsers  map[string]CommandParser
+	fallback CommandParser
+}
+
+// DefaultRegistry 内置命令的注册表, 未通过 WithRegistry 指定注册表的 Client 均使用它
+var DefaultRegistry = NewRegistry()
+
+// 尚未支持的命令:
+// USER_TOAST_MSG   续费了舰长
+// NOTICE_MSG       在本房间续费了舰长
+// ANCHOR_LOT_START 天选之人开始完整信息
+// ANCHOR_LOT_END   天选之人获奖id
+// ANCHOR_LOT_AWARD 天选之人获奖完整信息
+func init() {
+	for cmd, p := range map[string]CommandParser{
+		"DANMU_MSG":              parseDanmaku,
+		"SUPER_CHAT_MESSAGE":     parseSuperChat,
+		"SUPER_CHAT_MESSAGE_JPN": parseSuperChat,
+		"COMBO_SEND":             parseComboGift,
+		"SEND_GIFT":              parseGift,
+		"GUARD_BUY":              parseGuardBuy,
+		"INTERACT_WORD":          parseInteractWord,
+		"INTERACT_WORD_V2":       parseInteractWordV2,
+		"WATCHED_CHANGE":         parseWatchedChange,
+		"LIKE_INFO_V3_UPDATE":    parseLikeInfo,
+		"ONLINE_RANK_COUNT":      parseOnlineRankCount,
+	} {
+		DefaultRegistry.Register(cmd, p)
+	}
+	// 其余命令 ("LIVE" "PREPARING" "ONLINE_RANK_V2" "WIDGET_BANNER" 等) 原样转发
+	DefaultRegistry.SetFallback(parseRaw)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{parsers: make(map[string]CommandParser)}
+}
+
+// Register 在 DefaultRegistry 中注册命令解析函数
+func Register(cmd string, p CommandParser) {
+	DefaultRegistry.Register(cmd, p)
+}
+
+// Register 注册命令解析函数, 同名命令会被覆盖
+func (r *Registry) Register(cmd string, p CommandParser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parsers[cmd] = p
+}
+
+// Unregister 移除命令解析函数
+func (r *Registry) Unregister(cmd string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.parsers, cmd)
+}
+
+// SetFallback 设置未注册命令的解析函数, 为 nil 时丢弃未注册的命令
+func (r *Registry) SetFallback(p CommandParser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = p
+}
+
+// Lookup 查找命令的解析函数, 兼容 "DANMU_MSG:4:0:2:2:2:0" 形式的旧命令名
+func (r *Registry) Lookup(cmd string) (CommandParser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.parsers[cmd]; ok {
+		return p, true
+	}
+	if name, _, found := strings.Cut(cmd, ":"); found {
+		if p, ok := r.parsers[name]; ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Clone 复制注册表, 用于在 DefaultRegistry 基础上为单个 Client 定制
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nr := &Registry{
+		parsers:  make(map[string]CommandParser, len(r.parsers)),
+		fallback: r.fallback,
+	}
+	for cmd, p := range r.parsers {
+		nr.parsers[cmd] = p
+	}
+	return nr
+}
+
+// Parse 解析一条业务消息, 命令未注册且没有 fallback 时 ok 为 false
+func (r *Registry) Parse(body gjson.Result) (msg client.Message, ok bool, err error) {
+	p, ok := r.Lookup(body.Get("cmd").String())
+	if !ok {
+		r.mu.RLock()
+		p = r.fallback
+		r.mu.RUnlock()
+		if p == nil {
+			return msg, false, nil
+		}
+	}
+	msg, err = p(body)
+	return msg, true, err
+}
@@ -0,0 +1,123 @@
+package bilibili
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+	"github.com/BYT0723/go-tools/logx"
+)
+
+var ErrReadOnly = errors.New("replay client is read-only")
+
+// ReplayClient 将录制文件中的帧按原有节奏重新解码, 实现 client.Client
+type ReplayClient struct {
+	path     string
+	speed    float64
+	decoder  *packet.Decoder
+	registry *Registry
+	emotes   *EmoteTable
+
+	msgCh chan client.Message
+
+	ctx context.Context
+	cf  context.CancelFunc
+}
+
+// NewReplayClient 创建回放客户端, speed 为 1 时按实时回放, 大于 1 时加速, 小于等于 0 时不等待立即回放
+func NewReplayClient(path string, speed float64) *ReplayClient {
+	return &ReplayClient{
+		path:     path,
+		speed:    speed,
+		decoder:  packet.NewDecoder(),
+		registry: DefaultRegistry,
+		emotes:   NewEmoteTable(),
+		msgCh:    make(chan client.Message, 1024),
+	}
+}
+
+func (c *ReplayClient) Start(ctx context.Context) error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	rr, err := NewRecordReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	c.ctx, c.cf = context.WithCancel(ctx)
+	go func() {
+		defer f.Close()
+		defer close(c.msgCh)
+		c.replay(rr)
+	}()
+	return nil
+}
+
+func (c *ReplayClient) Stop() error {
+	if c.cf != nil {
+		c.cf()
+	}
+	return nil
+}
+
+func (c *ReplayClient) Receive() <-chan client.Message {
+	return c.msgCh
+}
+
+func (c *ReplayClient) Send(content string, opts client.SendOptions) (uint64, error) {
+	return 0, ErrReadOnly
+}
+
+func (c *ReplayClient) DanmakuConfig() (*client.DanmakuConfig, error) {
+	return nil, ErrReadOnly
+}
+
+func (c *ReplayClient) Emoticons() ([]client.EmoticonPackage, error) {
+	return nil, ErrReadOnly
+}
+
+// Emotes 回放直播间的表情表, 回放时不联网, 由调用方加入缓存的表情包
+func (c *ReplayClient) Emotes() *EmoteTable {
+	return c.emotes
+}
+
+func (c *ReplayClient) replay(rr *RecordReader) {
+	var prev time.Time
+	for {
+		t, frame, err := rr.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				logx.Errorf("replay %s, err: %v", c.path, err)
+			}
+			return
+		}
+
+		if c.speed > 0 && !prev.IsZero() {
+			if wait := time.Duration(float64(t.Sub(prev)) / c.speed); wait > 0 {
+				select {
+				case <-c.ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+		prev = t
+
+		dispatchFrame(c.decoder, c.registry, t, frame, func(msg client.Message) {
+			select {
+			case <-c.ctx.Done():
+			case c.msgCh <- msg:
+			}
+		})
+		if c.ctx.Err() != nil {
+			return
+		}
+	}
+}
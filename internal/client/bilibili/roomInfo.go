@@ -10,11 +10,17 @@ type (
 		Title          string        `json:"title,omitempty"`
 		ParentAreaName string        `json:"parent_area_name,omitempty"`
 		AreaName       string        `json:"area_name,omitempty"`
-		Online         string        `json:"online,omitempty"`    // 在线人数
-		Watched        string        `json:"watched,omitempty"`   // 累计观看
-		Liked          string        `json:"liked,omitempty"`     // 点赞数
-		Attention      int64         `json:"attention,omitempty"` // 关注数
-		Uptime         time.Duration `json:"time,omitempty"`      // 在线时间
+		Online         string        `json:"online,omitempty"`     // 在线人数
+		Watched        string        `json:"watched,omitempty"`    // 累计观看
+		Liked          string        `json:"liked,omitempty"`      // 点赞数
+		Popularity     int64         `json:"popularity,omitempty"` // 人气值
+		Attention      int64         `json:"attention,omitempty"`  // 关注数
+		Uptime         time.Duration `json:"time,omitempty"`       // 在线时间
+		LiveTime       time.Time     `json:"live_time,omitempty"`  // 开播时间, 未开播时为零值
+	}
+	Popularity struct {
+		Value int64
+		T     time.Time
 	}
 	OnlineRankUser struct {
 		Name  string
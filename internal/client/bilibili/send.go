@@ -0,0 +1,285 @@
+package bilibili
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/go-tools/logx"
+	"github.com/iyear/biligo"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultColor    = 0xFFFFFF
+	defaultFontSize = 25
+
+	// defaultSendInterval 两条弹幕之间的最小间隔
+	defaultSendInterval = time.Second
+	// defaultMaxLength 无法获取用户弹幕长度上限时使用的值
+	defaultMaxLength = 20
+	// defaultSendRetries 可重试错误的默认重试次数
+	defaultSendRetries = 2
+	// sendQueueSize 等待发送的弹幕数上限
+	sendQueueSize = 64
+)
+
+var (
+	// ErrSendQueueFull 等待发送的弹幕过多
+	ErrSendQueueFull = errors.New("send queue is full")
+	// ErrNotStarted 客户端未启动或已停止
+	ErrNotStarted = errors.New("client is not running")
+)
+
+// retryableCodes 可以稍后重试的发送错误码
+var retryableCodes = map[int64]bool{
+	10030: true, // 发送频率过快
+	10031: true, // 发送频率过快
+	-509:  true, // 请求过于频繁
+}
+
+// SendResult 一条弹幕 (或拆分后的一部分) 的发送结果
+type SendResult struct {
+	ID       uint64 // Send 返回的编号, 拆分后的各部分编号相同
+	Part     int    // 第几部分, 从 1 开始
+	Parts    int    // 共拆分为几部分
+	Content  string // 本部分的内容
+	Options  client.SendOptions
+	Code     int64  // 服务端返回的错误码, 0 为成功
+	Message  string // 服务端返回的错误信息
+	Filtered bool   // 服务端接受但因屏蔽词未广播
+	Err      error  // 网络等非服务端错误
+	Attempts int    // 尝试次数
+	T        time.Time
+}
+
+// OK 是否发送成功且未被屏蔽
+func (r *SendResult) OK() bool {
+	return r.Err == nil && r.Code == 0 && !r.Filtered
+}
+
+func (r *SendResult) Error() string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case r.Code != 0:
+		return fmt.Sprintf("(%d) %s", r.Code, r.Message)
+	case r.Filtered && r.Message == "k":
+		return "弹幕包含直播间指定屏蔽词"
+	case r.Filtered:
+		return "弹幕包含屏蔽词"
+	default:
+		return ""
+	}
+}
+
+// sendJob 一次 Send 调用
+type sendJob struct {
+	id      uint64
+	content string
+	opts    client.SendOptions
+}
+
+// Send 将弹幕加入发送队列并立即返回编号, 发送结果以 BiliBiliSendResult 消息返回
+func (c *Client) Send(content string, opts client.SendOptions) (uint64, error) {
+	if c.ctx == nil || c.ctx.Err() != nil {
+		return 0, ErrNotStarted
+	}
+	job := &sendJob{id: c.sendSeq.Add(1), content: content, opts: opts}
+	select {
+	case c.sendCh <- job:
+		return job.id, nil
+	default:
+		return 0, ErrSendQueueFull
+	}
+}
+
+// runSender 按最小间隔依次发送队列中的弹幕, 超出长度上限的弹幕拆分发送
+func (c *Client) runSender() {
+	var last time.Time
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case job := <-c.sendCh:
+			if c.maxLength <= 0 {
+				c.maxLength = c.queryMaxLength()
+			}
+			parts := []string{job.content}
+			// 图片表情以编号发送, 不拆分
+			if job.opts.Emoticon == "" {
+				parts = splitContent(job.content, c.maxLength)
+			}
+			for i, part := range parts {
+				result := &SendResult{ID: job.id, Part: i + 1, Parts: len(parts), Content: part, Options: job.opts}
+				c.expectEcho(result)
+				for result.Attempts <= c.sendRetries {
+					if wait := time.Until(last.Add(c.sendInterval * time.Duration(result.Attempts+1))); wait > 0 {
+						select {
+						case <-c.ctx.Done():
+							return
+						case <-time.After(wait):
+						}
+					}
+					result.Attempts++
+					result.Code, result.Message, result.Filtered, result.Err = c.sendDanmaku(part, job.opts)
+					last = time.Now()
+					if result.Err == nil && !retryableCodes[result.Code] {
+						break
+					}
+					logx.Errorf("send danmaku attempt %d, err: %s", result.Attempts, result.Error())
+				}
+				result.T = time.Now()
+				c.settleEcho(result)
+				c.queue.Push(client.Message{Type: client.BiliBiliSendResult, Data: result})
+			}
+		}
+	}
+}
+
+// sendDanmaku 调用发送接口, code 与 message 为服务端返回的结果, err 为请求本身的错误
+func (c *Client) sendDanmaku(content string, opts client.SendOptions) (code int64, message string, filtered bool, err error) {
+	color := int64(defaultColor)
+	if opts.Color != nil {
+		color = *opts.Color
+	}
+	if opts.Mode == 0 {
+		opts.Mode = client.ModeScroll
+	}
+	if opts.FontSize == 0 {
+		opts.FontSize = defaultFontSize
+	}
+
+	payload := map[string]string{
+		"roomid":   strconv.FormatInt(int64(c.roomID), 10),
+		"color":    strconv.FormatInt(color, 10),
+		"fontsize": strconv.Itoa(opts.FontSize),
+		"mode":     strconv.Itoa(int(opts.Mode)),
+		"msg":      content,
+		"bubble":   "0",
+		"rnd":      strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	if opts.ReplyTo != 0 {
+		payload["reply_mid"] = strconv.FormatInt(opts.ReplyTo, 10)
+	}
+	if opts.Emoticon != "" {
+		payload["msg"] = opts.Emoticon
+		payload["dm_type"] = "1"
+		payload["emoticonOptions"] = "[object Object]"
+	}
+
+	raw, err := c.cli.Raw(biligo.BiliLiveURL, "msg/send", "POST", payload)
+	if err != nil {
+		return 0, "", false, err
+	}
+	resp := gjson.ParseBytes(raw)
+	if !resp.Get("code").Exists() {
+		return 0, "", false, fmt.Errorf("unexpected response: %s", raw)
+	}
+	code, message = resp.Get("code").Int(), resp.Get("message").String()
+	// 成功时 message 为 f 或 k 表示弹幕因屏蔽词未广播
+	return code, message, code == 0 && (message == "f" || message == "k"), nil
+}
+
+// queryMaxLength 查询当前用户在直播间的弹幕长度上限
+func (c *Client) queryMaxLength() int {
+	resp, err := c.cli.RawParse(biligo.BiliLiveURL, "xlive/web-room/v1/index/getInfoByUser", "GET", map[string]string{
+		"room_id": strconv.FormatInt(int64(c.roomID), 10),
+	})
+	if err != nil {
+		logx.Errorf("get danmaku length limit, err: %v", err)
+		return defaultMaxLength
+	}
+	if n := gjson.GetBytes(resp.Data, "property.danmu.length").Int(); n > 0 {
+		return int(n)
+	}
+	return defaultMaxLength
+}
+
+// splitContent 按字符数拆分弹幕
+func splitContent(content string, limit int) []string {
+	runes := []rune(content)
+	if limit <= 0 || len(runes) <= limit {
+		return []string{content}
+	}
+	parts := make([]string, 0, (len(runes)+limit-1)/limit)
+	for len(runes) > 0 {
+		n := min(limit, len(runes))
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return parts
+}
+
+// DanmakuConfig 查询当前用户在直播间可用的弹幕颜色与位置, 粉丝勋章等解锁的颜色也在其中
+func (c *Client) DanmakuConfig() (*client.DanmakuConfig, error) {
+	resp, err := c.cli.RawParse(biligo.BiliLiveURL, "xlive/web-room/v1/dM/GetDMConfigByGroup", "GET", map[string]string{
+		"room_id": strconv.FormatInt(int64(c.roomID), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data = gjson.ParseBytes(resp.Data)
+		cfg  = new(client.DanmakuConfig)
+	)
+	for _, group := range data.Get("group").Array() {
+		for _, color := range group.Get("color").Array() {
+			// color 为十进制字符串, 部分分组只提供 color_hex
+			value, err := strconv.ParseInt(color.Get("color").String(), 10, 64)
+			if err != nil {
+				if value, err = strconv.ParseInt(color.Get("color_hex").String(), 16, 64); err != nil {
+					continue
+				}
+			}
+			cfg.Colors = append(cfg.Colors, client.DanmakuColor{
+				Name:      color.Get("name").String(),
+				Color:     value,
+				Available: color.Get("status").Int() == 1,
+			})
+		}
+	}
+	for _, mode := range data.Get("mode").Array() {
+		cfg.Modes = append(cfg.Modes, client.DanmakuModeOption{
+			Name:      mode.Get("name").String(),
+			Mode:      client.DanmakuMode(mode.Get("mode").Int()),
+			Available: mode.Get("status").Int() == 1,
+		})
+	}
+	return cfg, nil
+}
+
+// fetchEmoticons 查询当前用户在直播间可用的表情包, 包括通用表情、直播间与主播专属表情
+func (c *Client) fetchEmoticons() ([]client.EmoticonPackage, error) {
+	resp, err := c.cli.RawParse(biligo.BiliLiveURL, "xlive/web-ugc/v2/emoticon/GetEmoticons", "GET", map[string]string{
+		"platform": "pc",
+		"room_id":  strconv.FormatInt(int64(c.roomID), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []client.EmoticonPackage
+	for _, pkg := range gjson.GetBytes(resp.Data, "data").Array() {
+		p := client.EmoticonPackage{
+			ID:   pkg.Get("pkg_id").Int(),
+			Name: pkg.Get("pkg_name").String(),
+		}
+		// pkg_type 为 1 的通用表情以文字发送, 其余为图片表情
+		sticker := pkg.Get("pkg_type").Int() != 1
+		for _, e := range pkg.Get("emoticons").Array() {
+			p.Emoticons = append(p.Emoticons, client.Emoticon{
+				Name:      e.Get("emoji").String(),
+				Unique:    e.Get("emoticon_unique").String(),
+				URL:       e.Get("url").String(),
+				Sticker:   sticker,
+				Available: e.Get("perm").Int() == 1,
+			})
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,131 @@
+package bilibili
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+)
+
+// waitResult 等待编号为 id 的发送结果
+func waitResult(t *testing.T, c *Client, id uint64) *SendResult {
+	t.Helper()
+	msg := waitFor(t, c, "send result", func(msg client.Message) bool {
+		r, ok := msg.Data.(*SendResult)
+		return ok && r.ID == id
+	})
+	return msg.Data.(*SendResult)
+}
+
+func TestSendColor(t *testing.T) {
+	var (
+		srv    = newMock()
+		colors = make(chan string, 1)
+	)
+	c := startClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/msg/send" {
+			colors <- r.FormValue("color")
+		}
+		srv.ServeHTTP(w, r)
+	}))
+
+	black, red := int64(0), int64(0xFF0000)
+	tests := []struct {
+		name  string
+		color *int64
+		want  string
+	}{
+		{"default", nil, "16777215"},
+		{"black", &black, "0"},
+		{"red", &red, "16711680"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := c.Send(tt.name, client.SendOptions{Color: tt.color})
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-colors:
+				if got != tt.want {
+					t.Errorf("color = %s, want %s", got, tt.want)
+				}
+			case <-time.After(waitTimeout):
+				t.Fatal("timeout waiting for send request")
+			}
+			if r := waitResult(t, c, id); !r.OK() {
+				t.Errorf("result = %+v, want OK", r)
+			}
+		})
+	}
+}
+
+func TestSplitContent(t *testing.T) {
+	tests := []struct {
+		content string
+		limit   int
+		want    []string
+	}{
+		{"abc", 0, []string{"abc"}},
+		{"abc", 3, []string{"abc"}},
+		{"abcd", 3, []string{"abc", "d"}},
+		{"abcdef", 3, []string{"abc", "def"}},
+		// 按字符而不是字节拆分
+		{"晚上好呀", 3, []string{"晚上好", "呀"}},
+		{"晚上好", 3, []string{"晚上好"}},
+	}
+	for _, tt := range tests {
+		if got := splitContent(tt.content, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitContent(%q, %d) = %q, want %q", tt.content, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int64
+		fail     int // 前几次发送返回 code
+		wantCode int64
+		attempts int
+	}{
+		{"10030 retried", 10030, 1, 0, 2},
+		{"10031 retried", 10031, 2, 0, 3},
+		{"-509 retried", -509, 1, 0, 2},
+		{"10030 gives up", 10030, 3, 10030, 3},
+		{"-509 gives up", -509, 5, -509, 3},
+		// 不可重试的错误码直接返回
+		{"not retryable", 1003, 1, 1003, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				srv  = newMock()
+				sent atomic.Int32
+			)
+			c := startClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/msg/send" && int(sent.Add(1)) <= tt.fail {
+					fmt.Fprintf(w, `{"code":%d,"message":"频率过快","data":{}}`, tt.code)
+					return
+				}
+				srv.ServeHTTP(w, r)
+			}), WithSendRetries(2))
+
+			id, err := c.Send("你好", client.SendOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := waitResult(t, c, id)
+			if r.Code != tt.wantCode || r.Attempts != tt.attempts {
+				t.Errorf("result code %d attempts %d, want code %d attempts %d", r.Code, r.Attempts, tt.wantCode, tt.attempts)
+			}
+			if got := int(sent.Load()); got != tt.attempts {
+				t.Errorf("server received %d sends, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package bilibili
+
+import "time"
+
+type StatsKind int
+
+const (
+	StatsWatched StatsKind = iota // 累计观看
+	StatsLiked                    // 点赞数
+	StatsOnline                   // 高能用户数
+)
+
+// StatsUpdate 房间统计数据变化
+type StatsUpdate struct {
+	Kind  StatsKind
+	Value int64
+	Text  string // 服务端给出的展示文本, 如 "1.2万人看过"
+	T     time.Time
+}
@@ -0,0 +1,14 @@
+package bilibili
+
+import "time"
+
+// SuperChat 醒目留言, 价格单位为元
+type SuperChat struct {
+	ID       int64
+	UID      int64
+	Author   string
+	Content  string
+	Price    int64
+	Duration time.Duration // 展示时长
+	T        time.Time
+}
@@ -6,7 +6,18 @@ type Client interface {
 	Start(ctx context.Context) error
 	Stop() error
 	Receive() <-chan Message
-	Send(content string) error
+	// Send 将弹幕加入发送队列, 返回的编号用于匹配之后的发送结果消息
+	Send(content string, opts SendOptions) (uint64, error)
+	// DanmakuConfig 查询当前用户可用的弹幕颜色与位置
+	DanmakuConfig() (*DanmakuConfig, error)
+	// Emoticons 查询当前用户在直播间可用的表情包
+	Emoticons() ([]EmoticonPackage, error)
+}
+
+// Room 一个直播间及其客户端
+type Room struct {
+	ID     int64
+	Client Client
 }
 
 type MessageType int
@@ -16,6 +27,17 @@ const (
 	BiliBiliDanmaku
 	BiliBiliRoomInfo
 	BiliBiliRankInfo
+	BiliBiliConnState
+	BiliBiliPopularity
+	BiliBiliSuperChat
+	BiliBiliGift
+	BiliBiliGuardPurchase
+	BiliBiliUserEnter
+	BiliBiliStatsUpdate
+	BiliBiliRaw
+	BiliBiliDropped
+	BiliBiliSendResult
+	BiliBiliSendEcho
 )
 
 type Message struct {
@@ -0,0 +1,55 @@
+package client
+
+// DanmakuMode 弹幕显示位置
+type DanmakuMode int
+
+const (
+	ModeScroll DanmakuMode = 1 // 滚动
+	ModeBottom DanmakuMode = 4 // 底部
+	ModeTop    DanmakuMode = 5 // 顶部
+)
+
+// SendOptions 发送弹幕的可选设置, 零值字段使用默认值
+type SendOptions struct {
+	Color    *int64      // RGB 颜色, 如 0xFFFFFF, 为 nil 时使用默认颜色
+	Mode     DanmakuMode // 显示位置
+	FontSize int         // 字号
+	ReplyTo  int64       // 回复的用户 uid
+	Emoticon string      // 表情编号, 不为空时发送图片表情, 内容为表情名称
+}
+
+// DanmakuColor 一种弹幕颜色
+type DanmakuColor struct {
+	Name      string
+	Color     int64
+	Available bool // 当前用户是否已解锁
+}
+
+// DanmakuModeOption 一种弹幕位置
+type DanmakuModeOption struct {
+	Name      string
+	Mode      DanmakuMode
+	Available bool
+}
+
+// DanmakuConfig 当前用户在直播间可用的弹幕颜色与位置
+type DanmakuConfig struct {
+	Colors []DanmakuColor
+	Modes  []DanmakuModeOption
+}
+
+// Emoticon 一个表情
+type Emoticon struct {
+	Name      string // 表情名称, 文字表情如 [dog]
+	Unique    string // 表情编号, 发送表情弹幕时使用
+	URL       string
+	Sticker   bool // 是否为图片表情, 否则以名称作为文字发送
+	Available bool // 当前用户是否可用
+}
+
+// EmoticonPackage 一组表情, 如通用表情、直播间专属表情
+type EmoticonPackage struct {
+	ID        int64
+	Name      string
+	Emoticons []Emoticon
+}
@@ -0,0 +1,35 @@
+package client
+
+import "context"
+
+// tap 在消息交给使用方之前先回调 fn, 用于将同一消息流分发给其他消费者
+type tap struct {
+	Client
+	fn func(Message)
+	ch chan Message
+}
+
+// Tap 包装客户端, Receive 返回的每条消息都会先经过 fn, fn 不应阻塞
+func Tap(c Client, fn func(Message)) Client {
+	return &tap{Client: c, fn: fn, ch: make(chan Message, 1024)}
+}
+
+func (t *tap) Start(ctx context.Context) error {
+	if err := t.Client.Start(ctx); err != nil {
+		return err
+	}
+	go t.forward()
+	return nil
+}
+
+func (t *tap) forward() {
+	defer close(t.ch)
+	for msg := range t.Client.Receive() {
+		t.fn(msg)
+		t.ch <- msg
+	}
+}
+
+func (t *tap) Receive() <-chan Message {
+	return t.ch
+}
@@ -0,0 +1,15 @@
+package config
+
+import "path/filepath"
+
+type Archive struct {
+	// Disable 关闭后不再保存新的聊天记录, 搜索与导出只能使用已有的记录
+	Disable bool `cfg:"disable"`
+	// RetentionDays 记录保留天数, 0 表示永久保留
+	RetentionDays int `cfg:"retention_days"`
+}
+
+// ArchiveDir 聊天记录目录
+func ArchiveDir() string {
+	return filepath.Join(Dir, "archive")
+}
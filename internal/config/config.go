@@ -11,26 +11,75 @@ import (
 	"github.com/BYT0723/go-tools/logx"
 )
 
-var Config Configuration
+var (
+	Config Configuration
+	// Dir 配置目录, 聊天记录等数据也保存在此目录下
+	Dir string
+)
 
 type Configuration struct {
-	Cookie  string  `cfg:"cookie"`
-	RoomID  int64   `cfg:"room_id"`
-	History History `cfg:"history"`
-	Emote   Emote   `cfg:"emote"`
+	Cookie    string    `cfg:"cookie"`
+	RoomID    int64     `cfg:"room_id"`
+	Rooms     []int64   `cfg:"rooms"`
+	Protover  uint8     `cfg:"protover"`
+	Overflow  string    `cfg:"overflow"`
+	Endpoints Endpoints `cfg:"endpoints"`
+	Relay     string    `cfg:"relay"`
+	History   History   `cfg:"history"`
+	Render    Render    `cfg:"render"`
+	Send      Send      `cfg:"send"`
+	Emote     Emote     `cfg:"emote"`
+	Archive   Archive   `cfg:"archive"`
+
+	// RelayOrigins 允许连接转发服务的其他页面来源, 默认只允许同源页面
+	RelayOrigins []string `cfg:"relay_origins"`
 }
 
 const cfgTemplate = `cookie: xxx
 room_id: 0
+# 同时观看多个直播间, 设置后忽略 room_id
+# rooms: [0, 0]
+# 弹幕协议版本, 2 为 zlib 压缩, 3 为 brotli 压缩
+protover: 3
+# 界面处理不及时导致消息积压时的策略, 默认为 block:
+# block 等待, drop_oldest 丢弃最早的消息, drop_low_priority 先丢弃进房等低优先级消息
+# 醒目留言与礼物不会被丢弃
+# overflow: block
 emote:
   disable: false
+  # 直播间表情列表缓存在配置目录的 emote 下, 过期后重新获取
+  # cache_hours: 24
+  # 自定义 表情代码 -> Unicode 映射, 如 {"dog": "🐶"}, 优先于内置映射, 默认为配置目录下的 emote.json
+  # override: ""
+# 界面刷新, 弹幕过多时可降低刷新率或积压上限
+# render:
+#   max_fps: 20
+#   backlog: 256
+# 发送弹幕, interval_ms 为最小发送间隔, 超过 max_length 的弹幕拆分发送 (0 为向服务端查询),
+# 发送频率过快等错误重试 retries 次
+# send:
+#   interval_ms: 1000
+#   max_length: 0
+#   retries: 2
+# 聊天记录按直播间与场次保存在配置目录的 archive 下, 用于搜索与导出
+archive:
+  disable: false
+  # 保留天数, 0 为永久保留
+  retention_days: 30
+# bilibili 服务地址, 留空使用官方地址, 可指向本地的模拟服务
+# endpoints:
+#   api: http://127.0.0.1:8080
+#   live: http://127.0.0.1:8080
+#   stream: ws://127.0.0.1:8080/sub
+# 在本地端口转发弹幕事件 (SSE: /events, WebSocket: /ws), 浏览器源加载 http://<地址>/ 即为弹幕悬浮窗
+# relay: 127.0.0.1:9090
+# 允许连接转发服务的其他页面来源, 如 ["https://example.com"], "*" 为允许所有来源, 默认只允许同源页面与 OBS 浏览器源
+# relay_origins: []
 `
 
 func init() {
-	var (
-		dir     = getConfigDir("bilichat")
-		cfgPath = filepath.Join(dir, "config.yaml")
-	)
+	Dir = getConfigDir("bilichat")
+	cfgPath := filepath.Join(Dir, "config.yaml")
 
 	if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
 		_ = os.MkdirAll(filepath.Dir(cfgPath), 0o700)
@@ -46,11 +95,17 @@ func init() {
 
 	cfg.Init(
 		cfg.WithConfigName("config"),
-		cfg.WithConfigPath(".", dir),
+		cfg.WithConfigPath(".", Dir),
 		cfg.WithConfigType("yaml"),
 		cfg.WithDefaultUnMarshal(&Config),
 	)
 
+	if Config.Protover == 0 {
+		Config.Protover = 3
+	}
+	if Config.Overflow == "" {
+		Config.Overflow = "block"
+	}
 	if Config.History.Danmaku == 0 {
 		Config.History.Danmaku = 1024
 	}
@@ -60,6 +115,15 @@ func init() {
 	if Config.History.Gift == 0 {
 		Config.History.Gift = 512
 	}
+	if Config.Render.MaxFPS <= 0 {
+		Config.Render.MaxFPS = 20
+	}
+	if Config.Render.Backlog <= 0 {
+		Config.Render.Backlog = 256
+	}
+	if Config.Emote.CacheHours <= 0 {
+		Config.Emote.CacheHours = 24
+	}
 
 	if err := logx.Init(logx.WithConf(&logx.Config{
 		Name:       "bilichat",
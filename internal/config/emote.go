@@ -1,5 +1,24 @@
 package config
 
+import "path/filepath"
+
 type Emote struct {
 	Disable bool `cfg:"disable"`
+	// Override 用户自定义的 表情代码 -> Unicode 映射文件, 留空使用配置目录下的 emote.json
+	Override string `cfg:"override"`
+	// CacheHours 直播间表情列表缓存的有效时间
+	CacheHours int `cfg:"cache_hours"`
+}
+
+// EmoteCacheDir 直播间表情列表缓存目录
+func EmoteCacheDir() string {
+	return filepath.Join(Dir, "emote")
+}
+
+// EmoteOverridePath 用户自定义表情映射文件
+func EmoteOverridePath() string {
+	if Config.Emote.Override != "" {
+		return Config.Emote.Override
+	}
+	return filepath.Join(Dir, "emote.json")
 }
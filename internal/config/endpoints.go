@@ -0,0 +1,10 @@
+package config
+
+// Endpoints bilibili 服务地址, 留空使用官方地址
+type Endpoints struct {
+	API      string `cfg:"api"`
+	Live     string `cfg:"live"`
+	Origin   string `cfg:"origin"`
+	Stream   string `cfg:"stream"`
+	Insecure bool   `cfg:"insecure"`
+}
@@ -0,0 +1,8 @@
+package config
+
+type Render struct {
+	// MaxFPS 每秒最多重绘次数
+	MaxFPS int `cfg:"max_fps"`
+	// Backlog 一批消息中弹幕超过该数量时只显示最新的部分, 其余汇总为一行提示
+	Backlog int `cfg:"backlog"`
+}
@@ -0,0 +1,8 @@
+package config
+
+// Send 发送弹幕的设置, 指针字段为 nil 时使用客户端默认值, 显式设置的 0 也会生效
+type Send struct {
+	IntervalMS *int `cfg:"interval_ms"`
+	MaxLength  int  `cfg:"max_length"`
+	Retries    *int `cfg:"retries"`
+}
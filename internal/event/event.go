@@ -0,0 +1,174 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+)
+
+// Type 事件类型, 作为 JSON 输出中的 type 字段, 取值保持稳定
+type Type string
+
+const (
+	TypeDanmaku    Type = "danmaku"
+	TypeSuperChat  Type = "superchat"
+	TypeGift       Type = "gift"
+	TypeGuard      Type = "guard"
+	TypeEnter      Type = "enter"
+	TypeFollow     Type = "follow"
+	TypeShare      Type = "share"
+	TypeStats      Type = "stats"
+	TypePopularity Type = "popularity"
+	TypeRoomInfo   Type = "room_info"
+	TypeRank       Type = "rank"
+	TypeConnState  Type = "conn_state"
+	TypeDropped    Type = "dropped"
+	TypeSendResult Type = "send_result"
+	TypeSendEcho   Type = "send_echo"
+	TypeRaw        Type = "raw"
+)
+
+// Event 对外输出的事件, 字段含义不随 client.Message 的内部结构变化
+type Event struct {
+	Type      Type      `json:"type"`
+	Room      int64     `json:"room"`
+	User      string    `json:"user"`
+	UID       int64     `json:"uid"`
+	Content   string    `json:"content"`
+	Price     float64   `json:"price"` // 单位为元, 免费礼物为 0
+	Num       int64     `json:"num,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data,omitempty"` // 无法归入以上字段的附加信息
+}
+
+// FromMessage 将客户端消息转换为事件, 不支持的消息类型返回 false
+func FromMessage(room int64, msg client.Message) (Event, bool) {
+	e := Event{Room: room, Timestamp: time.Now()}
+
+	switch v := msg.Data.(type) {
+	case *bilibili.Danmaku:
+		e.Type = TypeDanmaku
+		e.User, e.UID, e.Content, e.Timestamp = v.Author, v.UID, v.Content, v.T
+		data := make(map[string]any)
+		if v.Medal != nil {
+			data["medal"], data["medal_level"] = v.Medal.Name, v.Medal.Level
+		}
+		if v.Emoticon != "" {
+			data["emoticon"] = v.Emoticon
+		}
+		if len(data) > 0 {
+			e.Data = data
+		}
+	case *bilibili.SuperChat:
+		e.Type = TypeSuperChat
+		e.User, e.UID, e.Content, e.Timestamp = v.Author, v.UID, v.Content, v.T
+		e.Price = float64(v.Price)
+	case *bilibili.Gift:
+		e.Type = TypeGift
+		e.User, e.UID, e.Content, e.Timestamp = v.Author, v.UID, v.GiftName, v.T
+		e.Num = v.Num
+		if v.CoinType == "gold" {
+			e.Price = float64(v.TotalCoin) / 1000
+		}
+	case *bilibili.GuardPurchase:
+		e.Type = TypeGuard
+		e.User, e.UID, e.Content, e.Timestamp = v.Author, v.UID, v.GiftName, v.T
+		e.Num = v.Num
+		e.Price = float64(v.Price*v.Num) / 1000
+	case *bilibili.UserEnter:
+		switch v.Type {
+		case bilibili.InteractFollow:
+			e.Type = TypeFollow
+		case bilibili.InteractShare:
+			e.Type = TypeShare
+		default:
+			e.Type = TypeEnter
+		}
+		e.User, e.UID, e.Timestamp = v.Author, v.UID, v.T
+	case *bilibili.StatsUpdate:
+		e.Type = TypeStats
+		e.Content, e.Timestamp = v.Text, v.T
+		var kind string
+		switch v.Kind {
+		case bilibili.StatsWatched:
+			kind = "watched"
+		case bilibili.StatsLiked:
+			kind = "liked"
+		case bilibili.StatsOnline:
+			kind = "online"
+		}
+		e.Data = map[string]any{"kind": kind, "value": v.Value}
+	case *bilibili.Popularity:
+		e.Type = TypePopularity
+		e.Timestamp = v.T
+		e.Data = map[string]any{"value": v.Value}
+	case *bilibili.RoomInfo:
+		e.Type = TypeRoomInfo
+		e.User, e.UID, e.Content = v.Uname, int64(v.UID), v.Title
+		e.Data = v
+	case []*bilibili.OnlineRankUser:
+		e.Type = TypeRank
+		rank := make([]map[string]any, len(v))
+		for i, u := range v {
+			rank[i] = map[string]any{"name": u.Name, "score": u.Score, "rank": u.Rank}
+		}
+		e.Data = rank
+	case *bilibili.ConnState:
+		e.Type = TypeConnState
+		state := "reconnected"
+		if v.State == bilibili.ConnReconnecting {
+			state = "reconnecting"
+		}
+		data := map[string]any{"state": state, "attempt": v.Attempt, "delay": v.Delay.String()}
+		if v.Err != nil {
+			data["error"] = v.Err.Error()
+		}
+		e.Data = data
+	case *bilibili.DropStats:
+		e.Type = TypeDropped
+		e.Data = map[string]any{"total": v.Total}
+	case *bilibili.SendResult:
+		e.Type = TypeSendResult
+		e.Content, e.Timestamp = v.Content, v.T
+		data := map[string]any{
+			"id": v.ID, "part": v.Part, "parts": v.Parts, "attempts": v.Attempts,
+			"ok": v.OK(), "code": v.Code, "message": v.Message, "filtered": v.Filtered,
+		}
+		if v.Err != nil {
+			data["error"] = v.Err.Error()
+		}
+		e.Data = data
+	case *bilibili.SendEcho:
+		e.Type = TypeSendEcho
+		e.Content, e.Timestamp = v.Content, v.T
+		e.Data = map[string]any{"id": v.ID, "part": v.Part, "parts": v.Parts, "delivered": v.Delivered}
+	case *bilibili.RawEvent:
+		e.Type = TypeRaw
+		e.Content, e.Timestamp = v.Cmd, v.T
+		e.Data = json.RawMessage(v.Body.Raw)
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// Summary 事件内容的简短描述, 用于文本输出
+func (e *Event) Summary() string {
+	switch e.Type {
+	case TypeSuperChat:
+		return fmt.Sprintf("[¥ %g] %s", e.Price, e.Content)
+	case TypeGift, TypeGuard:
+		return fmt.Sprintf("%d * %s", e.Num, e.Content)
+	case TypeEnter:
+		return "进入直播间"
+	case TypeFollow:
+		return "关注了直播间"
+	case TypeShare:
+		return "分享了直播间"
+	default:
+		return e.Content
+	}
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/mattn/go-runewidth"
+)
+
+// ASSOptions 弹幕字幕的画面与字体设置
+type ASSOptions struct {
+	Width    int           // 画面宽度
+	Height   int           // 画面高度
+	FontName string        // 字体
+	FontSize int           // 字号
+	Duration time.Duration // 每条弹幕从右侧进入到完全离开左侧的时间
+	Area     float64       // 弹幕占用的画面高度比例, 0 到 1
+	Alpha    int           // 透明度, 0 不透明, 255 完全透明
+}
+
+// DefaultASSOptions 与 bilibili 播放器默认设置接近的 1080p 参数
+var DefaultASSOptions = ASSOptions{
+	Width:    1920,
+	Height:   1080,
+	FontName: "Microsoft YaHei",
+	FontSize: 50,
+	Duration: 8 * time.Second,
+	Area:     1,
+	Alpha:    40,
+}
+
+// Validate 检查会导致弹道无法计算的参数
+func (o *ASSOptions) Validate() error {
+	switch {
+	case o.Width <= 0 || o.Height <= 0:
+		return fmt.Errorf("invalid ass resolution %dx%d", o.Width, o.Height)
+	case o.FontSize <= 0:
+		return fmt.Errorf("invalid ass font size %d", o.FontSize)
+	case o.Duration <= 0:
+		return fmt.Errorf("invalid ass scroll duration %v", o.Duration)
+	case o.Area <= 0 || o.Area > 1:
+		return fmt.Errorf("invalid ass area %v, must be in (0, 1]", o.Area)
+	}
+	return nil
+}
+
+// track 一条弹道上最后一条弹幕的位置信息
+type track struct {
+	start time.Duration // 进入画面的时间
+	width float64       // 文字宽度
+	used  bool
+}
+
+// speed 弹幕滚动速度, 越长的弹幕越快, 与 bilibili 播放器一致
+func (o *ASSOptions) speed(width float64) float64 {
+	return (float64(o.Width) + width) / o.Duration.Seconds()
+}
+
+// fits 在 t 时刻放入宽 width 的弹幕是否与弹道上的上一条弹幕重叠:
+// 上一条必须已经完全进入画面, 且新弹幕在上一条离开画面前追不上它
+func (o *ASSOptions) fits(tr *track, t time.Duration, width float64) bool {
+	if !tr.used {
+		return true
+	}
+	elapsed := (t - tr.start).Seconds()
+	if elapsed*o.speed(tr.width) < tr.width {
+		return false
+	}
+	return (t - tr.start + time.Duration(float64(o.Width)/o.speed(width)*float64(time.Second))) >= o.Duration
+}
+
+// textWidth 按全角字符等于字号估算文字宽度
+func (o *ASSOptions) textWidth(s string) float64 {
+	return float64(runewidth.StringWidth(s)) * float64(o.FontSize) / 2
+}
+
+// ASS 将弹幕与醒目留言排布为从右向左滚动的弹幕字幕, 早于 start 的事件被忽略.
+// 弹道选择与 bilibili 播放器相同: 从上到下选择第一条不会重叠的弹道,
+// 所有弹道都会重叠时选择上一条弹幕最早进入画面的弹道.
+func ASS(w io.Writer, events []event.Event, start time.Time, o ASSOptions) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	lineHeight := o.FontSize + o.FontSize/5
+	tracks := make([]track, max(1, int(float64(o.Height)*o.Area)/lineHeight))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `[Script Info]
+ScriptType: v4.00+
+PlayResX: %d
+PlayResY: %d
+WrapStyle: 2
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Danmaku,%s,%d,&H%02XFFFFFF,&H%02XFFFFFF,&H%02X000000,&H%02X000000,0,0,0,0,100,100,0,0,1,1,0,7,0,0,0,1
+Style: SuperChat,%s,%d,&H%02X5FD7FF,&H%02X5FD7FF,&H%02X000000,&H%02X000000,1,0,0,0,100,100,0,0,1,1,0,7,0,0,0,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`,
+		o.Width, o.Height,
+		o.FontName, o.FontSize, o.Alpha, o.Alpha, o.Alpha, o.Alpha,
+		o.FontName, o.FontSize, o.Alpha, o.Alpha, o.Alpha, o.Alpha,
+	)
+
+	for _, e := range events {
+		var (
+			style = "Danmaku"
+			text  = e.Content
+		)
+		switch e.Type {
+		case event.TypeDanmaku:
+		case event.TypeSuperChat:
+			style, text = "SuperChat", e.User+": "+e.Summary()
+		default:
+			continue
+		}
+		offset := e.Timestamp.Sub(start)
+		if offset < 0 || text == "" {
+			continue
+		}
+
+		width := o.textWidth(text)
+		idx := -1
+		for i := range tracks {
+			if o.fits(&tracks[i], offset, width) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			idx = 0
+			for i := range tracks {
+				if tracks[i].start < tracks[idx].start {
+					idx = i
+				}
+			}
+		}
+		tracks[idx] = track{start: offset, width: width, used: true}
+
+		y := idx * lineHeight
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,%s,%s,0,0,0,,{\\move(%d,%d,%d,%d)}%s\n",
+			formatASSTime(offset), formatASSTime(offset+o.Duration),
+			style, escapeASS(e.User),
+			o.Width, y, -int(width), y,
+			escapeASS(text),
+		)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatASSTime(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d:%02d.%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000/10)
+}
+
+// escapeASS 避免内容被解析为样式标签或换行
+var escapeASS = strings.NewReplacer(
+	"\\", "\\\\",
+	"{", "\\{",
+	"}", "\\}",
+	"\n", " ",
+	"\r", "",
+).Replace
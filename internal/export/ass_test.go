@@ -0,0 +1,81 @@
+package export
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+func TestASSInvalidOptions(t *testing.T) {
+	events := []event.Event{{Type: event.TypeDanmaku, Content: "hi", Timestamp: start}}
+	tests := []struct {
+		name string
+		set  func(o *ASSOptions)
+	}{
+		{"zero font size", func(o *ASSOptions) { o.FontSize = 0 }},
+		{"zero scroll", func(o *ASSOptions) { o.Duration = 0 }},
+		{"zero width", func(o *ASSOptions) { o.Width = 0 }},
+		{"zero area", func(o *ASSOptions) { o.Area = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultASSOptions
+			tt.set(&o)
+			// 参数无效时返回错误, 不能因除以零而崩溃
+			if err := ASS(io.Discard, events, start, o); err == nil {
+				t.Error("ASS returned nil error")
+			}
+		})
+	}
+}
+
+func TestASSTracks(t *testing.T) {
+	// 两条弹道, 宽 4 的弹幕速度为 12/s, 约 1.67s 后完全进入画面
+	o := ASSOptions{Width: 100, Height: 30, FontName: "sans", FontSize: 10, Duration: 10 * time.Second, Area: 1}
+	danmaku := func(d time.Duration, content string) event.Event {
+		return event.Event{Type: event.TypeDanmaku, User: "u", Content: content, Timestamp: at(d)}
+	}
+	events := []event.Event{
+		danmaku(-time.Second, "early"),
+		danmaku(0, "aaaa"),
+		// 第一条弹道上的弹幕尚未完全进入画面, 使用第二条
+		danmaku(500*time.Millisecond, "aaaa"),
+		// 所有弹道都会重叠, 使用上一条弹幕最早进入画面的弹道
+		danmaku(time.Second, "aaaa"),
+		{Type: event.TypeGift, User: "u", Content: "gift", Num: 1, Timestamp: at(2 * time.Second)},
+		// 上一条已经完全进入画面, 重新使用第一条弹道
+		danmaku(5*time.Second, "aaaa"),
+		// 第一条弹道重叠, 第二条弹道上的弹幕离开画面前追不上它
+		danmaku(5500*time.Millisecond, "aaaaaaaaaa"),
+	}
+
+	var b strings.Builder
+	if err := ASS(&b, events, start, o); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for line := range strings.Lines(b.String()) {
+		if strings.HasPrefix(line, "Dialogue:") {
+			got = append(got, line)
+		}
+	}
+
+	want := []string{
+		"Dialogue: 0,0:00:00.00,0:00:10.00,Danmaku,u,0,0,0,,{\\move(100,0,-20,0)}aaaa\n",
+		"Dialogue: 0,0:00:00.50,0:00:10.50,Danmaku,u,0,0,0,,{\\move(100,12,-20,12)}aaaa\n",
+		"Dialogue: 0,0:00:01.00,0:00:11.00,Danmaku,u,0,0,0,,{\\move(100,0,-20,0)}aaaa\n",
+		"Dialogue: 0,0:00:05.00,0:00:15.00,Danmaku,u,0,0,0,,{\\move(100,0,-20,0)}aaaa\n",
+		"Dialogue: 0,0:00:05.50,0:00:15.50,Danmaku,u,0,0,0,,{\\move(100,12,-50,12)}aaaaaaaaaa\n",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ASS dialogues =\n%s\nwant\n%s", strings.Join(got, ""), strings.Join(want, ""))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dialogue %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package export 将聊天记录导出为 CSV, JSON 以及按开播时间对齐的 SRT 与 ASS 字幕.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+// Formats 支持的导出格式
+var Formats = []string{"csv", "json", "srt", "ass"}
+
+// CSV 每个事件一行, offset 为相对 start 的时间
+func CSV(w io.Writer, events []event.Event, start time.Time) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"timestamp", "offset", "room", "type", "user", "uid", "content", "price", "num"})
+	for _, e := range events {
+		_ = cw.Write([]string{
+			e.Timestamp.Local().Format(time.RFC3339),
+			formatOffset(e.Timestamp.Sub(start)),
+			strconv.FormatInt(e.Room, 10),
+			string(e.Type),
+			e.User,
+			strconv.FormatInt(e.UID, 10),
+			e.Content,
+			strconv.FormatFloat(e.Price, 'f', -1, 64),
+			strconv.FormatInt(e.Num, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// JSON 事件数组
+func JSON(w io.Writer, events []event.Event) error {
+	if events == nil {
+		events = []event.Event{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
+
+// SRT 每个事件显示 duration, 早于 start 的事件被忽略
+func SRT(w io.Writer, events []event.Event, start time.Time, duration time.Duration) error {
+	var n int
+	for _, e := range events {
+		offset := e.Timestamp.Sub(start)
+		if offset < 0 {
+			continue
+		}
+		n++
+		if _, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s: %s\n\n",
+			n,
+			formatSRTTime(offset), formatSRTTime(offset+duration),
+			e.User, e.Summary(),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatOffset 相对时间, 如 01:02:03.456, 负数表示开播前
+func formatOffset(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000)
+}
+
+func formatSRTTime(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000)
+}
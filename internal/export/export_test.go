@@ -0,0 +1,62 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+var start = time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local)
+
+// at 相对 start 的事件时间
+func at(d time.Duration) time.Time {
+	return start.Add(d)
+}
+
+var events = []event.Event{
+	{Type: event.TypeDanmaku, Room: 1000, User: "early", UID: 3, Content: "before", Timestamp: at(-5 * time.Second)},
+	{Type: event.TypeDanmaku, Room: 1000, User: "alice", UID: 1, Content: "hello, world", Timestamp: at(1500 * time.Millisecond)},
+	{Type: event.TypeSuperChat, Room: 1000, User: "bob", UID: 2, Content: "thanks", Price: 30, Timestamp: at(time.Hour + 2*time.Minute + 3456*time.Millisecond)},
+}
+
+func TestCSV(t *testing.T) {
+	var b strings.Builder
+	if err := CSV(&b, events, start); err != nil {
+		t.Fatal(err)
+	}
+
+	// 开播前的事件以负数偏移保留
+	ts := func(i int) string { return events[i].Timestamp.Format(time.RFC3339) }
+	want := fmt.Sprintf(`timestamp,offset,room,type,user,uid,content,price,num
+%s,-00:00:05.000,1000,danmaku,early,3,before,0,0
+%s,00:00:01.500,1000,danmaku,alice,1,"hello, world",0,0
+%s,01:02:03.456,1000,superchat,bob,2,thanks,30,0
+`, ts(0), ts(1), ts(2))
+	if got := b.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSRT(t *testing.T) {
+	var b strings.Builder
+	if err := SRT(&b, events, start, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// 开播前的事件被忽略, 序号从 1 开始连续编号
+	want := `1
+00:00:01,500 --> 00:00:06,500
+alice: hello, world
+
+2
+01:02:03,456 --> 01:02:08,456
+bob: [¥ 30] thanks
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("SRT =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package mock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type mockUser struct {
+	uid   int64
+	uname string
+}
+
+var (
+	startedAt = time.Now().Add(-42 * time.Minute)
+
+	mockUsers = []mockUser{
+		{20001, "路过的观众"},
+		{20002, "老粉丝"},
+		{20003, "舰长大人"},
+		{20004, "摸鱼的打工人"},
+	}
+)
+
+func mustJSON(v any) []byte {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+// unixMilli 零值时间返回 0, 使客户端使用接收时间
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// DanmakuBody 构造 DANMU_MSG 消息
+func DanmakuBody(uid int64, uname, content string, t time.Time) []byte {
+	meta := make([]any, 16)
+	meta[4] = unixMilli(t)
+	meta[15] = map[string]any{
+		"user": map[string]any{
+			"medal": map[string]any{"name": "模拟", "level": uid % 30},
+		},
+	}
+	return mustJSON(map[string]any{
+		"cmd":  "DANMU_MSG",
+		"info": []any{meta, content, []any{uid, uname}},
+	})
+}
+
+// EmoticonBody 构造图片表情的 DANMU_MSG 消息, content 为表情名称
+func EmoticonBody(uid int64, uname, content, unique string, t time.Time) []byte {
+	meta := make([]any, 16)
+	meta[4] = unixMilli(t)
+	meta[12] = 1
+	meta[13] = map[string]any{"emoticon_unique": unique}
+	return mustJSON(map[string]any{
+		"cmd":  "DANMU_MSG",
+		"info": []any{meta, content, []any{uid, uname}},
+	})
+}
+
+// GiftBody 构造 SEND_GIFT 消息, price 单位为金瓜子
+func GiftBody(uid int64, uname, giftName string, giftID, num, price int64) []byte {
+	return mustJSON(map[string]any{
+		"cmd": "SEND_GIFT",
+		"data": map[string]any{
+			"uid":        uid,
+			"uname":      uname,
+			"action":     "投喂",
+			"giftId":     giftID,
+			"giftName":   giftName,
+			"num":        num,
+			"price":      price,
+			"total_coin": price * num,
+			"coin_type":  "gold",
+		},
+	})
+}
+
+// SuperChatBody 构造 SUPER_CHAT_MESSAGE 消息, price 单位为元
+func SuperChatBody(id, uid int64, uname, message string, price int64) []byte {
+	return mustJSON(map[string]any{
+		"cmd": "SUPER_CHAT_MESSAGE",
+		"data": map[string]any{
+			"id":        id,
+			"uid":       uid,
+			"message":   message,
+			"price":     price,
+			"time":      60,
+			"user_info": map[string]any{"uname": uname},
+		},
+	})
+}
+
+// InteractWordV2Body 构造 INTERACT_WORD_V2 消息, data.pb 为 protobuf 编码
+func InteractWordV2Body(uid int64, uname string, msgType int) []byte {
+	var pb []byte
+	pb = protowire.AppendTag(pb, 1, protowire.VarintType)
+	pb = protowire.AppendVarint(pb, uint64(uid))
+	pb = protowire.AppendTag(pb, 2, protowire.BytesType)
+	pb = protowire.AppendString(pb, uname)
+	pb = protowire.AppendTag(pb, 5, protowire.VarintType)
+	pb = protowire.AppendVarint(pb, uint64(msgType))
+
+	return mustJSON(map[string]any{
+		"cmd":  "INTERACT_WORD_V2",
+		"data": map[string]any{"pb": base64.StdEncoding.EncodeToString(pb)},
+	})
+}
+
+func historyBody(uid int64, uname, text string, t time.Time) []byte {
+	return mustJSON(map[string]any{
+		"uid":      uid,
+		"nickname": uname,
+		"text":     text,
+		"timeline": t.Format(time.DateTime),
+	})
+}
+
+// DefaultScript 默认剧本, 覆盖弹幕、礼物、醒目留言与进房
+func DefaultScript() [][]byte {
+	var (
+		a, b, c, d = mockUsers[0], mockUsers[1], mockUsers[2], mockUsers[3]
+		zero       time.Time
+	)
+	return [][]byte{
+		InteractWordV2Body(a.uid, a.uname, 1),
+		DanmakuBody(a.uid, a.uname, "主播晚上好[打call]", zero),
+		DanmakuBody(b.uid, b.uname, "来了来了", zero),
+		GiftBody(b.uid, b.uname, "辣条", 1, 10, 100),
+		InteractWordV2Body(d.uid, d.uname, 2),
+		DanmakuBody(d.uid, d.uname, "上班摸鱼看直播[吃瓜]", zero),
+		SuperChatBody(1, c.uid, c.uname, "主播今天唱什么歌?", 30),
+		GiftBody(c.uid, c.uname, "小心心", 30607, 1, 5000),
+		DanmakuBody(c.uid, c.uname, "哈哈哈哈哈哈", zero),
+		InteractWordV2Body(b.uid, b.uname, 3),
+	}
+}
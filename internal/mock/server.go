@@ -0,0 +1,319 @@
+// Package mock 实现一个模拟的 bilibili 直播服务, 包含 bilibili.Client 用到的
+// HTTP 接口与弹幕 WebSocket 协议, 用于离线测试与演示.
+package mock
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// UID 模拟登录用户的 uid
+	UID = 10000
+	// Uname 模拟登录用户的昵称
+	Uname = "bilichat"
+)
+
+type Server struct {
+	// Interval 推送剧本事件的间隔
+	Interval time.Duration
+	// BatchSize 每次推送的事件数
+	BatchSize int
+	// Script 循环推送的剧本事件, 每项为一条完整的业务消息 JSON
+	Script [][]byte
+	// SendInterval 两次发送弹幕的最小间隔, 过快时返回 10030 错误, 0 为不限制
+	SendInterval time.Duration
+	// MaxLength 用户的弹幕长度上限
+	MaxLength int
+	// ShadowWords 包含这些词的弹幕发送成功但不广播, 模拟被屏蔽的弹幕
+	ShadowWords []string
+
+	encoder    *packet.Encoder
+	upgrader   websocket.Upgrader
+	popularity atomic.Int64
+
+	mu       sync.Mutex
+	streams  map[*stream]struct{}
+	history  [][]byte
+	lastSend time.Time
+	mux      *http.ServeMux
+}
+
+func NewServer() *Server {
+	s := &Server{
+		Interval:  time.Second,
+		BatchSize: 3,
+		MaxLength: 20,
+		Script:    DefaultScript(),
+		encoder:   packet.NewEncoder(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		streams: make(map[*stream]struct{}),
+		mux:     http.NewServeMux(),
+	}
+	s.popularity.Store(1024)
+
+	s.mux.HandleFunc("/x/web-interface/nav", s.handleNav)
+	s.mux.HandleFunc("/x/member/web/account", s.handleAccount)
+	s.mux.HandleFunc("/x/click-interface/web/heartbeat", s.handleOK)
+	s.mux.HandleFunc("/xlive/web-room/v1/index/getDanmuInfo", s.handleDanmuInfo)
+	s.mux.HandleFunc("/xlive/web-room/v1/index/getRoomBaseInfo", s.handleRoomBaseInfo)
+	s.mux.HandleFunc("/xlive/general-interface/v1/rank/getOnlineGoldRank", s.handleRank)
+	s.mux.HandleFunc("/xlive/web-room/v1/dM/gethistory", s.handleHistory)
+	s.mux.HandleFunc("/xlive/web-room/v1/dM/GetDMConfigByGroup", s.handleDMConfig)
+	s.mux.HandleFunc("/xlive/web-room/v1/index/getInfoByUser", s.handleInfoByUser)
+	s.mux.HandleFunc("/xlive/web-ugc/v2/emoticon/GetEmoticons", s.handleEmoticons)
+	s.mux.HandleFunc("/msg/send", s.handleSend)
+	s.mux.HandleFunc("/sub", s.handleStream)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// nextPopularity 人气值随机波动, 不低于 0
+func (s *Server) nextPopularity(delta int64) int64 {
+	for {
+		old := s.popularity.Load()
+		next := max(old+delta, 0)
+		if s.popularity.CompareAndSwap(old, next) {
+			return next
+		}
+	}
+}
+
+// ListenAndServe 监听 addr 并提供服务, 直到出错
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s)
+}
+
+func writeJSON(w http.ResponseWriter, code int, message string, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"code":    code,
+		"message": message,
+		"data":    data,
+	})
+}
+
+func (s *Server) handleOK(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 0, "0", map[string]any{})
+}
+
+func (s *Server) handleNav(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 0, "0", map[string]any{
+		"isLogin": true,
+		"mid":     UID,
+		"uname":   Uname,
+		"wbi_img": map[string]any{
+			"img_url": "http://" + r.Host + "/bfs/wbi/7cd084941338484aae1ad9425b84077c.png",
+			"sub_url": "http://" + r.Host + "/bfs/wbi/4932caff0ff746eab6f01bf08b70ac45.png",
+		},
+	})
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 0, "0", map[string]any{
+		"mid":   UID,
+		"uname": Uname,
+	})
+}
+
+func (s *Server) handleDanmuInfo(w http.ResponseWriter, r *http.Request) {
+	host, portStr, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, portStr = r.Host, "80"
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	writeJSON(w, 0, "0", map[string]any{
+		"token": "mock-token",
+		"host_list": []map[string]any{
+			{"host": host, "port": port, "wss_port": port, "ws_port": port},
+		},
+	})
+}
+
+func (s *Server) handleRoomBaseInfo(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Query().Get("room_ids")
+	id, _ := strconv.ParseInt(roomID, 10, 64)
+
+	writeJSON(w, 0, "0", map[string]any{
+		"by_room_ids": map[string]any{
+			roomID: map[string]any{
+				"room_id":          id,
+				"uid":              UID + 1,
+				"uname":            "模拟主播",
+				"title":            "bilichat 模拟直播间",
+				"area_name":        "聊天电台",
+				"parent_area_name": "电台",
+				"attention":        12345,
+				"live_status":      1,
+				"live_time":        startedAt.Format(time.DateTime),
+			},
+		},
+	})
+}
+
+func (s *Server) handleRank(w http.ResponseWriter, r *http.Request) {
+	items := make([]map[string]any, 0, len(mockUsers))
+	for i, u := range mockUsers {
+		items = append(items, map[string]any{
+			"uid":      u.uid,
+			"name":     u.uname,
+			"score":    (len(mockUsers) - i) * 100,
+			"userRank": i + 1,
+		})
+	}
+	writeJSON(w, 0, "0", map[string]any{"OnlineRankItem": items})
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	room := make([]json.RawMessage, 0, len(s.history))
+	for _, h := range s.history {
+		room = append(room, h)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, 0, "0", map[string]any{"admin": []any{}, "room": room})
+}
+
+// handleDMConfig 返回弹幕颜色与位置, 模拟用户已解锁部分粉丝勋章颜色与顶部弹幕
+func (s *Server) handleDMConfig(w http.ResponseWriter, r *http.Request) {
+	color := func(name, hex string, status int) map[string]any {
+		v, _ := strconv.ParseInt(hex, 16, 64)
+		return map[string]any{"name": name, "color": strconv.FormatInt(v, 10), "color_hex": hex, "status": status}
+	}
+	writeJSON(w, 0, "0", map[string]any{
+		"group": []map[string]any{
+			{"name": "默认", "color": []any{color("白色", "FFFFFF", 1), color("红色", "FF6868", 1), color("蓝色", "6699CC", 1)}},
+			{"name": "粉丝勋章", "color": []any{color("粉丝粉", "FF6699", 1), color("舰长蓝", "00CFFF", 0)}},
+		},
+		"mode": []map[string]any{
+			{"name": "滚动", "mode": 1, "type": "mode", "status": 1},
+			{"name": "顶部", "mode": 5, "type": "mode", "status": 1},
+			{"name": "底部", "mode": 4, "type": "mode", "status": 0},
+		},
+	})
+}
+
+func (s *Server) handleInfoByUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 0, "0", map[string]any{
+		"property": map[string]any{
+			"danmu": map[string]any{"length": s.MaxLength},
+		},
+	})
+}
+
+// mockEmoticons 模拟的表情包, 通用表情以文字发送, 直播间专属表情为图片表情, 最后一个未解锁
+var mockEmoticons = []struct {
+	pkgID   int64
+	pkgName string
+	pkgType int
+	emojis  [][2]string // 名称与编号
+}{
+	{1, "通用表情", 1, [][2]string{{"[dog]", "official_1"}, {"[吃瓜]", "official_2"}, {"[妙]", "official_3"}}},
+	{2, "房间专属表情", 2, [][2]string{{"赞", "room_1_1"}, {"好耶", "room_1_2"}, {"晚安", "room_1_3"}}},
+}
+
+func (s *Server) handleEmoticons(w http.ResponseWriter, r *http.Request) {
+	pkgs := make([]map[string]any, 0, len(mockEmoticons))
+	for _, pkg := range mockEmoticons {
+		emoticons := make([]map[string]any, 0, len(pkg.emojis))
+		for i, e := range pkg.emojis {
+			perm := 1
+			if pkg.pkgType != 1 && i == len(pkg.emojis)-1 {
+				perm = 0
+			}
+			emoticons = append(emoticons, map[string]any{
+				"emoji":           e[0],
+				"descript":        e[0],
+				"emoticon_unique": e[1],
+				"url":             "http://" + r.Host + "/bfs/emote/" + e[1] + ".png",
+				"perm":            perm,
+			})
+		}
+		pkgs = append(pkgs, map[string]any{
+			"pkg_id":    pkg.pkgID,
+			"pkg_name":  pkg.pkgName,
+			"pkg_type":  pkg.pkgType,
+			"emoticons": emoticons,
+		})
+	}
+	writeJSON(w, 0, "0", map[string]any{"data": pkgs})
+}
+
+// emoticonName 按编号查找图片表情的名称
+func emoticonName(unique string) (string, bool) {
+	for _, pkg := range mockEmoticons {
+		for _, e := range pkg.emojis {
+			if e[1] == unique {
+				return e[0], true
+			}
+		}
+	}
+	return "", false
+}
+
+// handleSend 接收发送的弹幕, 并作为 DANMU_MSG 回显给所有连接, 包含屏蔽词的弹幕不回显
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, -400, err.Error(), nil)
+		return
+	}
+	msg := r.PostForm.Get("msg")
+	if msg == "" {
+		writeJSON(w, -400, "msg is empty", nil)
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	if s.SendInterval > 0 && now.Sub(s.lastSend) < s.SendInterval {
+		s.mu.Unlock()
+		writeJSON(w, 10030, "您发送弹幕的频率过快", nil)
+		return
+	}
+	// 图片表情的 msg 为表情编号, 回显时内容为表情名称
+	body := DanmakuBody(UID, Uname, msg, now)
+	if r.PostForm.Get("dm_type") == "1" {
+		name, ok := emoticonName(msg)
+		if !ok {
+			s.mu.Unlock()
+			writeJSON(w, -400, "表情不存在", nil)
+			return
+		}
+		body = EmoticonBody(UID, Uname, name, msg, now)
+	} else if n := len([]rune(msg)); n > s.MaxLength {
+		s.mu.Unlock()
+		writeJSON(w, 1003, "超出限制长度", nil)
+		return
+	}
+	s.lastSend = now
+	if slices.ContainsFunc(s.ShadowWords, func(w string) bool { return strings.Contains(msg, w) }) {
+		s.mu.Unlock()
+		writeJSON(w, 0, "", map[string]any{})
+		return
+	}
+	s.history = append(s.history, historyBody(UID, Uname, msg, now))
+	if len(s.history) > 10 {
+		s.history = s.history[len(s.history)-10:]
+	}
+	s.mu.Unlock()
+
+	s.Broadcast(body)
+	writeJSON(w, 0, "", map[string]any{})
+}
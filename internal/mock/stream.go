@@ -0,0 +1,152 @@
+package mock
+
+import (
+	"encoding/binary"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client/bilibili/packet"
+	"github.com/BYT0723/go-tools/logx"
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+)
+
+// stream 一个弹幕 WebSocket 连接
+type stream struct {
+	conn     *websocket.Conn
+	protover uint16
+	mu       sync.Mutex
+}
+
+func (st *stream) write(data []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Broadcast 向所有连接推送业务消息, 按各连接鉴权时的 protover 压缩
+func (s *Server) Broadcast(bodies ...[]byte) {
+	s.mu.Lock()
+	streams := make([]*stream, 0, len(s.streams))
+	for st := range s.streams {
+		streams = append(streams, st)
+	}
+	s.mu.Unlock()
+
+	for _, st := range streams {
+		if err := s.push(st, bodies...); err != nil {
+			logx.Errorf("mock push, err: %v", err)
+		}
+	}
+}
+
+// CloseStreams 断开所有弹幕连接, 模拟网络中断
+func (s *Server) CloseStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for st := range s.streams {
+		_ = st.conn.Close()
+	}
+}
+
+func (s *Server) push(st *stream, bodies ...[]byte) error {
+	switch st.protover {
+	case packet.VersionZlib, packet.VersionBrotli:
+		data, err := s.encoder.Compress(st.protover, bodies...)
+		if err != nil {
+			return err
+		}
+		return st.write(data)
+	default:
+		for _, body := range bodies {
+			if err := st.write(s.encoder.Encode(packet.VersionJSON, packet.OpMessage, body)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// 鉴权
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		return
+	}
+	auth, _, err := packet.Unmarshal(raw)
+	if err != nil || auth.Op != packet.OpAuth {
+		return
+	}
+
+	st := &stream{
+		conn:     conn,
+		protover: uint16(gjson.GetBytes(auth.Body, "protover").Int()),
+	}
+	if err := st.write(s.encoder.Encode(packet.VersionInt32, packet.OpAuthReply, []byte(`{"code":0}`))); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.streams[st] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, st)
+		s.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.playScript(st, done)
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		pkt, _, err := packet.Unmarshal(raw)
+		if err != nil {
+			continue
+		}
+		if pkt.Op == packet.OpHeartbeat {
+			body := binary.BigEndian.AppendUint32(nil, uint32(s.nextPopularity(time.Now().UnixNano()%100-40)))
+			if err := st.write(s.encoder.Encode(packet.VersionInt32, packet.OpHeartbeatReply, body)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// playScript 按 Interval 循环推送剧本事件
+func (s *Server) playScript(st *stream, done <-chan struct{}) {
+	if len(s.Script) == 0 || s.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	var idx int
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			bodies := make([][]byte, 0, s.BatchSize)
+			for range max(1, s.BatchSize) {
+				bodies = append(bodies, s.Script[idx%len(s.Script)])
+				idx++
+			}
+			if err := s.push(st, bodies...); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package relay 将解码后的直播事件通过本地 HTTP 端口以 Server-Sent Events 与
+// WebSocket 转发, 并附带一个可供 OBS 浏览器源加载的弹幕悬浮窗页面.
+package relay
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/BYT0723/go-tools/logx"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// subscriberBuffer 每个连接待发送事件的缓冲数, 写满后丢弃新事件, 避免慢连接拖慢其他连接
+	subscriberBuffer = 256
+	// shutdownTimeout 停止服务时等待连接关闭的时间
+	shutdownTimeout = 5 * time.Second
+)
+
+//go:embed overlay.html
+var overlayHTML []byte
+
+// subscriber 一个 SSE 或 WebSocket 连接
+type subscriber struct {
+	ch    chan []byte
+	types []event.Type // 为空时接收所有类型
+	rooms []int64      // 为空时接收所有直播间
+}
+
+// parseFilter 解析连接的过滤参数, 如 ?types=danmaku,superchat&room=123
+func parseFilter(r *http.Request) *subscriber {
+	sub := &subscriber{ch: make(chan []byte, subscriberBuffer)}
+	for _, v := range r.URL.Query()["types"] {
+		for t := range strings.SplitSeq(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				sub.types = append(sub.types, event.Type(t))
+			}
+		}
+	}
+	for _, v := range r.URL.Query()["room"] {
+		for id := range strings.SplitSeq(v, ",") {
+			if room, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64); err == nil {
+				sub.rooms = append(sub.rooms, room)
+			}
+		}
+	}
+	return sub
+}
+
+func (sub *subscriber) match(e *event.Event) bool {
+	return (len(sub.types) == 0 || slices.Contains(sub.types, e.Type)) &&
+		(len(sub.rooms) == 0 || slices.Contains(sub.rooms, e.Room))
+}
+
+type Server struct {
+	upgrader websocket.Upgrader
+	origins  []string
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+	mux  *http.ServeMux
+}
+
+type Option func(s *Server)
+
+// WithAllowedOrigins 允许来自 origins 的页面连接 SSE 与 WebSocket, 如 https://example.com, * 表示允许所有来源.
+// 默认只允许同源页面与不带 Origin 的客户端, 如悬浮窗页面与 OBS 浏览器源
+func WithAllowedOrigins(origins ...string) Option {
+	return func(s *Server) {
+		s.origins = origins
+	}
+}
+
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		subs: make(map[*subscriber]struct{}),
+		mux:  http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+
+	s.mux.HandleFunc("/", s.handleOverlay)
+	s.mux.HandleFunc("/events", s.handleSSE)
+	s.mux.HandleFunc("/ws", s.handleWebSocket)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe 监听 addr 并提供服务, 直到出错或 ctx 结束, ctx 结束时关闭所有连接并返回 nil
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: s,
+		// 连接的 context 随 ctx 结束, 使 SSE 与 WebSocket 连接退出
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		sctx, cf := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cf()
+		if err := srv.Shutdown(sctx); err != nil {
+			logx.Errorf("relay shutdown, err: %v", err)
+		}
+	}()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		// 等待已有的连接关闭
+		<-stopped
+		return nil
+	}
+	return err
+}
+
+// checkOrigin 检查 SSE 与 WebSocket 连接的来源页面
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(s.origins, "*") || slices.Contains(s.origins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Publish 将事件发送给所有匹配的连接, 不会阻塞
+func (s *Server) Publish(e event.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		logx.Errorf("relay marshal %s, err: %v", e.Type, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if !sub.match(&e) {
+			continue
+		}
+		select {
+		case sub.ch <- data:
+		default:
+		}
+	}
+}
+
+func (s *Server) subscribe(sub *subscriber) {
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	delete(s.subs, sub)
+	s.mu.Unlock()
+}
+
+func (s *Server) handleOverlay(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/overlay" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(overlayHTML)
+}
+
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := parseFilter(r)
+	s.subscribe(sub)
+	defer s.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// 只允许同源与允许列表中的页面跨域读取事件
+	if origin := r.Header.Get("Origin"); origin != "" && s.checkOrigin(r) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// 定期发送注释行保持连接, 避免被中间代理断开
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case data := <-sub.ch:
+			if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub := parseFilter(r)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.subscribe(sub)
+	defer s.unsubscribe(sub)
+
+	// 只用于发现连接关闭, 忽略客户端发来的内容
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case data := <-sub.ch:
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+	}
+}
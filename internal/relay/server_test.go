@@ -0,0 +1,228 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/gorilla/websocket"
+)
+
+const waitTimeout = 5 * time.Second
+
+// waitSubs 等待连接数达到 n, WebSocket 握手完成后才加入订阅
+func waitSubs(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		s.mu.Lock()
+		got := len(s.subs)
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d subscribers, got %d", n, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func dial(t *testing.T, ts *httptest.Server, query string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws"+query, header)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// readContents 读取 n 条事件的内容
+func readContents(t *testing.T, conn *websocket.Conn, n int) []string {
+	t.Helper()
+	var contents []string
+	_ = conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	for range n {
+		var e event.Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("read event %d, err: %v", len(contents), err)
+		}
+		contents = append(contents, e.Content)
+	}
+	return contents
+}
+
+func TestFilter(t *testing.T) {
+	s := NewServer()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	danmaku, _, err := dial(t, ts, "?types=danmaku", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	room2, _, err := dial(t, ts, "?room=2&types=danmaku,superchat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, _, err := dial(t, ts, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sse, err := http.Get(ts.URL + "/events?types=superchat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sse.Body.Close()
+	waitSubs(t, s, 4)
+
+	events := []event.Event{
+		{Type: event.TypeDanmaku, Room: 1, Content: "d1"},
+		{Type: event.TypeGift, Room: 2, Content: "g2"},
+		{Type: event.TypeSuperChat, Room: 1, Content: "s1"},
+		{Type: event.TypeDanmaku, Room: 2, Content: "d2"},
+		{Type: event.TypeSuperChat, Room: 2, Content: "s2"},
+	}
+	for _, e := range events {
+		s.Publish(e)
+	}
+
+	// 每个连接只收到满足自己过滤条件的事件
+	check := func(name string, got []string, want ...string) {
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s got %v, want %v", name, got, want)
+		}
+	}
+	check("types=danmaku", readContents(t, danmaku, 2), "d1", "d2")
+	check("room=2", readContents(t, room2, 2), "d2", "s2")
+	check("all", readContents(t, all, 5), "d1", "g2", "s1", "d2", "s2")
+
+	var got []string
+	scanner := bufio.NewScanner(sse.Body)
+	for len(got) < 2 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e event.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e.Content)
+	}
+	check("sse types=superchat", got, "s1", "s2")
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	s := NewServer()
+	var (
+		slow = &subscriber{ch: make(chan []byte, subscriberBuffer)}
+		fast = &subscriber{ch: make(chan []byte, 2*subscriberBuffer)}
+	)
+	s.subscribe(slow)
+	s.subscribe(fast)
+
+	// 不读取的连接写满后丢弃新事件, Publish 不阻塞, 其他连接不受影响
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range subscriberBuffer + 10 {
+			s.Publish(event.Event{Type: event.TypeDanmaku})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(waitTimeout):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+	if len(slow.ch) != subscriberBuffer {
+		t.Errorf("slow subscriber buffered %d events, want %d", len(slow.ch), subscriberBuffer)
+	}
+	if len(fast.ch) != subscriberBuffer+10 {
+		t.Errorf("fast subscriber buffered %d events, want %d", len(fast.ch), subscriberBuffer+10)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		ok      bool
+	}{
+		{"no origin", nil, "", true},
+		{"same origin", nil, "http://{host}", true},
+		{"other origin", nil, "https://evil.example", false},
+		{"allowed origin", []string{"https://overlay.example"}, "https://overlay.example", true},
+		{"not in allow list", []string{"https://overlay.example"}, "https://evil.example", false},
+		{"allow all", []string{"*"}, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(NewServer(WithAllowedOrigins(tt.allowed...)))
+			defer ts.Close()
+
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", strings.ReplaceAll(tt.origin, "{host}", strings.TrimPrefix(ts.URL, "http://")))
+			}
+			_, resp, err := dial(t, ts, "", header)
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("dial err = %v, want ok %v", err, tt.ok)
+			}
+			if !tt.ok && resp != nil && resp.StatusCode != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestListenAndServeShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := NewServer()
+	ctx, cf := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.ListenAndServe(ctx, addr) }()
+
+	// 连接建立后取消 ctx, 服务停止并断开已有的连接
+	var conn *websocket.Conn
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		if conn, _, err = websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer conn.Close()
+	waitSubs(t, s, 1)
+
+	cf()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ListenAndServe = %v, want nil", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("ListenAndServe did not return after ctx is done")
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("connection is still open after shutdown")
+	}
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var echoStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+
+// localEcho 已提交发送、尚未确认送达的弹幕, 显示在弹幕列表末尾
+type localEcho struct {
+	id      uint64
+	content string
+	t       time.Time
+	parts   int // 分段数, 收到第一条发送结果前为 0
+	sent    int // 已发送成功的分段数
+	settled int // 已确认送达或超时的分段数
+}
+
+// addEcho 记录提交发送的弹幕
+func (r *roomState) addEcho(id uint64, content string) {
+	r.echoes = append(r.echoes, &localEcho{id: id, content: content, t: time.Now()})
+}
+
+func (r *roomState) findEcho(id uint64) int {
+	return slices.IndexFunc(r.echoes, func(e *localEcho) bool { return e.id == id })
+}
+
+// handleSendResult 更新分段发送结果, 发送失败时不再等待送达
+func (r *roomState) handleSendResult(v *bilibili.SendResult) {
+	i := r.findEcho(v.ID)
+	if i < 0 {
+		return
+	}
+	if !v.OK() {
+		r.echoes = slices.Delete(r.echoes, i, i+1)
+		return
+	}
+	r.echoes[i].parts = v.Parts
+	r.echoes[i].sent++
+	r.settleEcho(i)
+}
+
+// handleSendEcho 更新分段送达结果, 返回超时未送达的提示, 全部分段确认后移除.
+// 广播可能早于发送结果到达, 此时先记下确认, 由 handleSendResult 移除
+func (r *roomState) handleSendEcho(v *bilibili.SendEcho) (warning string) {
+	if !v.Delivered {
+		warning = "弹幕可能被屏蔽: " + SanitizeViewportText(v.Content)
+	}
+	i := r.findEcho(v.ID)
+	if i < 0 {
+		return
+	}
+	e := r.echoes[i]
+	e.settled++
+	if e.parts == 0 {
+		e.parts = v.Parts
+	}
+	r.settleEcho(i)
+	return
+}
+
+// settleEcho 所有分段均已发送并确认后移除
+func (r *roomState) settleEcho(i int) {
+	if e := r.echoes[i]; e.parts > 0 && e.sent >= e.parts && e.settled >= e.parts {
+		r.echoes = slices.Delete(r.echoes, i, i+1)
+	}
+}
+
+// echoLines 待确认弹幕的显示内容
+func (m *App) echoLines(r *roomState) []string {
+	lines := make([]string, 0, len(r.echoes))
+	for _, e := range r.echoes {
+		state := "发送中…"
+		if e.parts > 0 && e.sent >= e.parts {
+			state = "已发送, 等待确认"
+		}
+		lines = append(lines, echoStyle.Render(fmt.Sprintf("%s 我: %s (%s)",
+			e.t.Format("[15:04]"), SanitizeViewportText(e.content), state)))
+	}
+	return lines
+}
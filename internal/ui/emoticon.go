@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/charmbracelet/lipgloss"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const emotePlaceholder = "搜索表情, ↑/↓ 选择, Enter 发送, Esc 取消"
+
+var (
+	emotePickerStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#00afff"))
+	emoteSelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00afff")).Bold(true)
+	emoteDisabledStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+	emotePackageStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#999999"))
+	stickerStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffaf5f"))
+)
+
+// emoticonsMsg 直播间表情包查询完成
+type emoticonsMsg struct {
+	idx  int
+	pkgs []client.EmoticonPackage
+	err  error
+}
+
+// fetchEmoticons 获取直播间表情包, 客户端启动时已加载
+func fetchEmoticons(idx int, r client.Room) tea.Cmd {
+	return func() tea.Msg {
+		pkgs, err := r.Client.Emoticons()
+		return emoticonsMsg{idx: idx, pkgs: pkgs, err: err}
+	}
+}
+
+// emoteItem 选择器中的一个表情
+type emoteItem struct {
+	client.Emoticon
+	pkg string
+}
+
+// emotePicker 表情选择器, 输入框内容作为搜索条件
+type emotePicker struct {
+	items   []emoteItem
+	matches []int // 按匹配程度排序的 items 下标
+	cursor  int
+	draft   string // 打开选择器前输入框的内容
+	query   string
+	loading bool
+	loadErr error
+	roomIdx int
+}
+
+// setPackages 展开表情包并重新筛选
+func (p *emotePicker) setPackages(pkgs []client.EmoticonPackage) {
+	p.items = p.items[:0]
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Emoticons {
+			p.items = append(p.items, emoteItem{Emoticon: e, pkg: pkg.Name})
+		}
+	}
+	p.filter(p.query)
+}
+
+// filter 按名称与表情包名称模糊匹配, 不可用的表情排在后面
+func (p *emotePicker) filter(query string) {
+	p.query = query
+	type scored struct{ idx, score int }
+	var res []scored
+	for i, item := range p.items {
+		score, ok := fuzzyScore(item.Name, query)
+		if !ok {
+			if score, ok = fuzzyScore(item.pkg, query); !ok {
+				continue
+			}
+			score -= 100
+		}
+		if !item.Available {
+			score -= 1000
+		}
+		res = append(res, scored{i, score})
+	}
+	slices.SortStableFunc(res, func(a, b scored) int { return b.score - a.score })
+
+	p.matches = p.matches[:0]
+	for _, r := range res {
+		p.matches = append(p.matches, r.idx)
+	}
+	p.cursor = 0
+}
+
+// move 移动选中的表情
+func (p *emotePicker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = (p.cursor + delta + len(p.matches)) % len(p.matches)
+}
+
+// selected 当前选中的表情
+func (p *emotePicker) selected() (emoteItem, bool) {
+	if len(p.matches) == 0 {
+		return emoteItem{}, false
+	}
+	return p.items[p.matches[p.cursor]], true
+}
+
+// fuzzyScore 判断 query 的字符是否按顺序出现在 s 中, 连续匹配与靠前的匹配得分更高
+func fuzzyScore(s, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	var (
+		text  = []rune(strings.ToLower(s))
+		q     = []rune(strings.ToLower(query))
+		score int
+		prev  = -2
+		j     int
+	)
+	for i := 0; i < len(text) && j < len(q); i++ {
+		if unicode.IsSpace(q[j]) {
+			j++
+			continue
+		}
+		if text[i] != q[j] {
+			continue
+		}
+		score += 10
+		if i == prev+1 {
+			score += 15
+		}
+		if j == 0 {
+			score -= i
+		}
+		prev = i
+		j++
+	}
+	if j < len(q) {
+		return 0, false
+	}
+	return score - len(text), true
+}
+
+// startEmotePicker 打开当前直播间的表情选择器, 表情包在首次打开时查询
+func (m *App) startEmotePicker() tea.Cmd {
+	r := m.room()
+	m.picker = &emotePicker{draft: m.inputArea.Value(), roomIdx: m.active}
+	m.mode = ModeEmote
+	m.inputArea.Reset()
+	m.inputArea.Prompt = "☺ "
+	m.inputArea.FocusedStyle.Prompt = lipgloss.NewStyle()
+	m.inputArea.Placeholder = emotePlaceholder
+	m.inputArea.Focus()
+
+	if r.emoticons != nil {
+		m.picker.setPackages(r.emoticons)
+		return nil
+	}
+	m.picker.loading = true
+	return fetchEmoticons(m.active, r.Room)
+}
+
+// endEmotePicker 关闭表情选择器, 恢复打开前的输入并追加 insert
+func (m *App) endEmotePicker(insert string) {
+	draft := m.picker.draft + insert
+	m.picker = nil
+	m.mode = ModeInput
+	m.inputArea.Reset()
+	m.inputArea.Placeholder = inputPlaceholder
+	m.inputArea.SetValue(draft)
+	m.inputArea.Focus()
+	m.updatePrompt()
+}
+
+// handleEmoticons 保存查询到的表情包
+func (m *App) handleEmoticons(msg emoticonsMsg) {
+	if msg.err == nil {
+		m.rooms[msg.idx].emoticons = msg.pkgs
+	}
+	if m.picker == nil || m.picker.roomIdx != msg.idx {
+		return
+	}
+	m.picker.loading = false
+	m.picker.loadErr = msg.err
+	m.picker.setPackages(msg.pkgs)
+}
+
+// chooseEmote 文字表情插入输入框, 图片表情直接发送
+func (m *App) chooseEmote() {
+	item, ok := m.picker.selected()
+	if !ok {
+		return
+	}
+	if !item.Available {
+		m.room().messages.Push(m.senderStyle.Render("system: ") + "表情未解锁: " + item.Name)
+		m.endEmotePicker("")
+		m.refreshMessages()
+		return
+	}
+	if !item.Sticker {
+		m.endEmotePicker(item.Name)
+		return
+	}
+
+	r := m.room()
+	opts := r.sendOpts
+	opts.Emoticon = item.Unique
+	id, err := r.Client.Send(item.Name, opts)
+	if err != nil {
+		r.messages.Push(m.senderStyle.Render("system: ") + "消息发送失败: " + err.Error())
+	} else {
+		r.addEcho(id, item.Name)
+	}
+	m.endEmotePicker("")
+	m.refreshMessages()
+	m.messageBox.GotoBottom()
+}
+
+// emotePickerView 在弹幕框的位置显示表情选择器
+func (m *App) emotePickerView() string {
+	var (
+		p      = m.picker
+		width  = max(m.messageBox.Width-2, 10)
+		height = max(m.messageBox.Height-2, 3)
+		lines  []string
+	)
+	switch {
+	case p.loading:
+		lines = append(lines, "正在获取表情…")
+	case p.loadErr != nil:
+		lines = append(lines, "获取表情失败: "+p.loadErr.Error())
+	default:
+		lines = append(lines, fmt.Sprintf("表情 %d/%d", len(p.matches), len(p.items)))
+	}
+
+	// 选中项保持在可见范围内
+	visible := height - 1
+	start := max(0, min(p.cursor-visible/2, len(p.matches)-visible))
+	for i := start; i < len(p.matches) && i < start+visible; i++ {
+		item := p.items[p.matches[i]]
+		kind := "文字"
+		if item.Sticker {
+			kind = "图片"
+		}
+		desc := item.pkg + " · " + kind
+		if !item.Available {
+			desc = item.pkg + " · 未解锁"
+		}
+		cursor, name := "  ", item.Name
+		switch {
+		case i == p.cursor:
+			cursor, name = emoteSelectedStyle.Render("> "), emoteSelectedStyle.Render(name)
+		case !item.Available:
+			name = emoteDisabledStyle.Render(name)
+		}
+		line := cursor + name + " " + emotePackageStyle.Render(desc)
+		lines = append(lines, line)
+	}
+
+	return emotePickerStyle.Width(width).Height(height).MaxHeight(height + 2).Render(strings.Join(lines, "\n"))
+}
@@ -5,4 +5,6 @@ type Mode uint8
 const (
 	ModeNormal Mode = iota
 	ModeInput
+	ModeSearch // 输入框用于输入搜索条件
+	ModeEmote  // 输入框用于搜索表情
 )
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dirty 等待重绘的视图
+type dirty uint8
+
+const (
+	dirtyMessages dirty = 1 << iota
+	dirtySC
+	dirtyGifts
+)
+
+// flushMsg 重绘等待中的视图
+type flushMsg struct{}
+
+// markDirty 标记视图等待重绘
+func (m *App) markDirty(d dirty) {
+	m.dirty |= d
+}
+
+// scheduleFlush 有等待重绘的视图且尚未安排重绘时, 按最大刷新率安排重绘,
+// 间隔内收到的消息合并为一次重绘
+func (m *App) scheduleFlush() tea.Cmd {
+	if m.dirty == 0 || m.flushPending {
+		return nil
+	}
+	m.flushPending = true
+	return tea.Tick(time.Second/time.Duration(config.Config.Render.MaxFPS), func(time.Time) tea.Msg { return flushMsg{} })
+}
+
+// flush 重绘所有等待中的视图
+func (m *App) flush() {
+	d := m.dirty
+	m.dirty, m.flushPending = 0, false
+
+	if d&dirtyMessages != 0 {
+		m.refreshMessages()
+	}
+	if d&dirtySC != 0 {
+		m.refreshSC()
+	}
+	if d&dirtyGifts != 0 {
+		m.refreshGifts()
+	}
+}
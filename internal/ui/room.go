@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+	"github.com/BYT0723/bilichat/internal/config"
+	"github.com/BYT0723/go-tools/ds"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// roomState 单个直播间的界面状态, 切换标签页时由 App 渲染到共享的视图中
+type roomState struct {
+	client.Room
+
+	info       bilibili.RoomInfo
+	connState  string
+	dropped    uint64                // 客户端因积压丢弃的消息数
+	popularity *ds.RingBuffer[int64] // 人气值变化趋势
+
+	messages *ds.RingBuffer[string]
+	sc       *ds.RingBuffer[string]
+	gifts    *ds.RingBuffer[string]
+	rank     []*bilibili.OnlineRankUser
+	interact string
+
+	// 未解析的命令及出现次数
+	rawCmds     map[string]int
+	rawCmdNames []string
+
+	// 非当前标签页时收到的弹幕、醒目留言与礼物数
+	unread int
+
+	// 弹幕配置与当前选择的发送设置
+	dmConfig *client.DanmakuConfig
+	sendOpts client.SendOptions
+	colorIdx int // 当前颜色在可用颜色中的位置, -1 表示未选择
+	modeIdx  int
+	// 最近发言用户的 昵称 -> uid, 用于按昵称回复
+	users map[string]int64
+	// 可用的表情包, 首次打开表情选择器时查询
+	emoticons []client.EmoticonPackage
+	// 该直播间的表情表, 由客户端提供
+	emotes *bilibili.EmoteTable
+	// 已提交发送、尚未确认送达的弹幕
+	echoes []*localEcho
+}
+
+func newRoomState(r client.Room) *roomState {
+	emotes := bilibili.NewEmoteTable()
+	if c, ok := r.Client.(interface{ Emotes() *bilibili.EmoteTable }); ok {
+		emotes = c.Emotes()
+	}
+	return &roomState{
+		Room:       r,
+		popularity: ds.NewRingBufferWithSize[int64](popularityHistory),
+		messages:   ds.NewRingBufferWithSize[string](config.Config.History.Danmaku),
+		sc:         ds.NewRingBufferWithSize[string](config.Config.History.SC),
+		gifts:      ds.NewRingBufferWithSize[string](config.Config.History.Gift),
+		rawCmds:    make(map[string]int),
+		users:      make(map[string]int64),
+		emotes:     emotes,
+		colorIdx:   -1,
+	}
+}
+
+// label 标签页名称, 房间信息获取前使用房间号
+func (r *roomState) label() string {
+	if r.info.Uname != "" {
+		return r.info.Uname
+	}
+	return "直播间 " + strconv.FormatInt(r.ID, 10)
+}
+
+// batchBudget 单次读取消息的最长时间, maxBatch 单次最多读取的消息数
+const (
+	batchBudget = 10 * time.Millisecond
+	maxBatch    = 4096
+)
+
+// roomMsg 附带来源标签页下标的一批客户端消息
+type roomMsg struct {
+	idx    int
+	msgs   []client.Message
+	closed bool // 客户端的消息通道已关闭
+}
+
+// listenRoom 等待指定标签页客户端的下一条消息, 随后在 batchBudget 内读取所有已到达的消息
+func listenRoom(idx int, c client.Client) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-c.Receive()
+		if !ok {
+			return roomMsg{idx: idx, closed: true}
+		}
+
+		batch := roomMsg{idx: idx, msgs: []client.Message{msg}}
+		deadline := time.Now().Add(batchBudget)
+		for len(batch.msgs) < maxBatch && time.Now().Before(deadline) {
+			select {
+			case msg, ok := <-c.Receive():
+				if !ok {
+					batch.closed = true
+					return batch
+				}
+				batch.msgs = append(batch.msgs, msg)
+			default:
+				return batch
+			}
+		}
+		return batch
+	}
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/archive"
+	"github.com/BYT0723/bilichat/internal/config"
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/charmbracelet/lipgloss"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// searchContext 每条搜索结果前后显示的事件数
+	searchContext = 2
+	// searchLimit 最多显示的搜索结果数
+	searchLimit = 200
+
+	inputPlaceholder  = "say something..."
+	searchPlaceholder = "user:名称 uid:123 type:danmaku,superchat since:7d until:2006-01-02 re:正则 关键字"
+)
+
+var searchContextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+
+// searchResultMsg 搜索完成
+type searchResultMsg struct {
+	query   string
+	results []archive.Result
+	err     error
+}
+
+// startSearch 将输入框切换为搜索条件输入
+func (m *App) startSearch() {
+	m.mode = ModeSearch
+	m.inputArea.Reset()
+	m.inputArea.Prompt = "/ "
+	m.inputArea.FocusedStyle.Prompt = lipgloss.NewStyle()
+	m.inputArea.Placeholder = searchPlaceholder
+	m.inputArea.Focus()
+}
+
+// endSearchInput 恢复输入框并回到普通模式
+func (m *App) endSearchInput() {
+	m.mode = ModeNormal
+	m.inputArea.Reset()
+	m.inputArea.Placeholder = inputPlaceholder
+	m.inputArea.Blur()
+	m.updatePrompt()
+}
+
+// search 在聊天记录中搜索, 未指定 room: 时只搜索当前直播间, 合并时间线下搜索所有直播间
+func (m *App) search(s string) tea.Cmd {
+	q, err := archive.ParseQuery(s, time.Now())
+	if err != nil {
+		return func() tea.Msg { return searchResultMsg{query: s, err: err} }
+	}
+	if len(q.Rooms) == 0 && !m.merged {
+		q.Rooms = []int64{m.room().ID}
+	}
+	return func() tea.Msg {
+		results, err := archive.Search(config.ArchiveDir(), q, searchContext, searchLimit)
+		return searchResultMsg{query: s, results: results, err: err}
+	}
+}
+
+// showSearch 在弹幕框中显示搜索结果, 普通模式下按 Esc 返回
+func (m *App) showSearch(msg searchResultMsg) {
+	header := fmt.Sprintf("搜索 %q: %d 条结果, Esc 返回", msg.query, len(msg.results))
+	if config.Config.Archive.Disable {
+		header += " (聊天记录已关闭, 新的消息不会被保存)"
+	}
+	if msg.err != nil {
+		header = fmt.Sprintf("搜索 %q 失败: %v", msg.query, msg.err)
+	}
+
+	lines := []string{m.senderStyle.Render("system: ") + header}
+	for i, r := range msg.results {
+		if i > 0 {
+			lines = append(lines, searchContextStyle.Render("--"))
+		}
+		for _, e := range r.Before {
+			lines = append(lines, searchContextStyle.Render(m.searchLine(&e, false)))
+		}
+		lines = append(lines, m.searchLine(&r.Event, true))
+		for _, e := range r.After {
+			lines = append(lines, searchContextStyle.Render(m.searchLine(&e, false)))
+		}
+	}
+	m.searchResults = lines
+	m.refreshMessages()
+}
+
+func (m *App) searchLine(e *event.Event, match bool) string {
+	var (
+		t    = e.Timestamp.Local().Format("[01-02 15:04]")
+		user = SanitizeViewportText(e.User) + ":"
+		kind string
+		room string
+		text = SanitizeViewportText(e.Summary())
+	)
+	if e.Type != event.TypeDanmaku {
+		kind = "<" + string(e.Type) + "> "
+	}
+	if m.merged {
+		room = fmt.Sprintf("[%d] ", e.Room)
+	}
+	if match {
+		t, user = m.timeStyle.Render(t), m.senderStyle.Render(user)
+	}
+	return fmt.Sprintf("%s %s%s%s %s", t, room, kind, user, text)
+}
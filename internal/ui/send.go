@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/charmbracelet/lipgloss"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxKnownUsers 用于按昵称回复的 昵称 -> uid 记录上限, 超出后清空重新记录
+const maxKnownUsers = 4096
+
+// modePrompts 各弹幕位置对应的输入框提示符
+var modePrompts = map[client.DanmakuMode]string{
+	client.ModeScroll: "┃ ",
+	client.ModeTop:    "▔ ",
+	client.ModeBottom: "▁ ",
+}
+
+// danmakuConfigMsg 直播间弹幕配置查询完成
+type danmakuConfigMsg struct {
+	idx int
+	cfg *client.DanmakuConfig
+	err error
+}
+
+func fetchDanmakuConfig(idx int, c client.Client) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := c.DanmakuConfig()
+		return danmakuConfigMsg{idx: idx, cfg: cfg, err: err}
+	}
+}
+
+// availableColors 当前用户已解锁的弹幕颜色
+func (r *roomState) availableColors() (colors []client.DanmakuColor) {
+	if r.dmConfig == nil {
+		return nil
+	}
+	for _, c := range r.dmConfig.Colors {
+		if c.Available {
+			colors = append(colors, c)
+		}
+	}
+	return
+}
+
+// availableModes 当前用户可用的弹幕位置
+func (r *roomState) availableModes() (modes []client.DanmakuModeOption) {
+	if r.dmConfig == nil {
+		return nil
+	}
+	for _, m := range r.dmConfig.Modes {
+		if m.Available {
+			modes = append(modes, m)
+		}
+	}
+	return
+}
+
+// cycleColor 切换到下一个可用颜色, 未选择颜色时切换到第一个
+func (m *App) cycleColor() {
+	r := m.room()
+	colors := r.availableColors()
+	if len(colors) == 0 {
+		return
+	}
+	r.colorIdx = (r.colorIdx + 1) % len(colors)
+	r.sendOpts.Color = &colors[r.colorIdx].Color
+	m.updatePrompt()
+}
+
+// cycleMode 切换到下一个可用位置
+func (m *App) cycleMode() {
+	r := m.room()
+	modes := r.availableModes()
+	if len(modes) == 0 {
+		return
+	}
+	r.modeIdx = (r.modeIdx + 1) % len(modes)
+	r.sendOpts.Mode = modes[r.modeIdx].Mode
+	m.updatePrompt()
+}
+
+// updatePrompt 用提示符的颜色与形状显示当前直播间选择的弹幕颜色与位置
+func (m *App) updatePrompt() {
+	if m.mode == ModeSearch || m.mode == ModeEmote {
+		return
+	}
+	opts := m.room().sendOpts
+
+	prompt, ok := modePrompts[opts.Mode]
+	if !ok {
+		prompt = modePrompts[client.ModeScroll]
+	}
+	m.inputArea.Prompt = prompt
+
+	style := lipgloss.NewStyle()
+	if opts.Color != nil {
+		style = style.Foreground(lipgloss.Color(fmt.Sprintf("#%06x", *opts.Color)))
+	}
+	m.inputArea.FocusedStyle.Prompt = style
+	m.inputArea.BlurredStyle.Prompt = style
+}
+
+// parseSendInput 解析输入框内容, 以 {key=value ...} 开头时作为本条弹幕的设置, 如
+//
+//	{color=红色 mode=top reply=老粉丝} 内容
+//
+// color 为颜色名称或十六进制值, mode 为 scroll/top/bottom, size 为字号, reply 为 uid 或最近发言的昵称
+func (m *App) parseSendInput(s string) (string, client.SendOptions, error) {
+	r := m.room()
+	opts := r.sendOpts
+
+	if !strings.HasPrefix(s, "{") {
+		return s, opts, nil
+	}
+	end := strings.Index(s, "}")
+	if end < 0 {
+		return s, opts, nil
+	}
+	content := strings.TrimSpace(s[end+1:])
+
+	for _, field := range strings.Fields(s[1:end]) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "color", "c":
+			color, err := r.parseColor(value)
+			if err != nil {
+				return "", opts, err
+			}
+			opts.Color = &color
+		case "mode", "m":
+			switch value {
+			case "scroll", "滚动":
+				opts.Mode = client.ModeScroll
+			case "top", "顶部":
+				opts.Mode = client.ModeTop
+			case "bottom", "底部":
+				opts.Mode = client.ModeBottom
+			default:
+				return "", opts, fmt.Errorf("未知的弹幕位置 %q", value)
+			}
+		case "size", "s":
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return "", opts, fmt.Errorf("无效的字号 %q", value)
+			}
+			opts.FontSize = size
+		case "reply", "r":
+			uid, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				var ok bool
+				if uid, ok = r.users[strings.TrimPrefix(value, "@")]; !ok {
+					return "", opts, fmt.Errorf("未找到用户 %q", value)
+				}
+			}
+			opts.ReplyTo = uid
+		default:
+			return "", opts, fmt.Errorf("未知的设置 %q", key)
+		}
+	}
+	if content == "" {
+		return "", opts, errors.New("弹幕内容为空")
+	}
+	return content, opts, nil
+}
+
+// parseColor 按名称查找已解锁的颜色, 或解析 #RRGGBB 格式的颜色
+func (r *roomState) parseColor(s string) (int64, error) {
+	for _, c := range r.availableColors() {
+		if c.Name == s {
+			return c.Color, nil
+		}
+	}
+	if v, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 16, 64); err == nil && len(strings.TrimPrefix(s, "#")) == 6 {
+		return v, nil
+	}
+	return 0, fmt.Errorf("未知的颜色 %q", s)
+}
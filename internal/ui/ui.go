@@ -2,8 +2,8 @@ package ui
 
 import (
 	"cmp"
-	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,7 +11,7 @@ import (
 	"github.com/BYT0723/bilichat/internal/client"
 	"github.com/BYT0723/bilichat/internal/client/bilibili"
 	"github.com/BYT0723/bilichat/internal/config"
-	"github.com/BYT0723/go-tools/ds"
+	"github.com/BYT0723/bilichat/internal/ui/view"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -21,13 +21,27 @@ import (
 )
 
 var (
-	cli client.Client
-
-	roomInfoHomeStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#00afff"))
-	roomInfoZoneStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
-	roomInfoOnlineStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#5fafff"))
-	roomInfoWatchedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffd700"))
-	roomInfoUptimeStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#999999"))
+	roomInfoHomeStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#00afff"))
+	roomInfoZoneStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+	roomInfoOnlineStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#5fafff"))
+	roomInfoWatchedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffd700"))
+	roomInfoUptimeStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#999999"))
+	roomInfoPopularityStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff8700"))
+	connStateStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f"))
+
+	tabStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666")).Padding(0, 1)
+	activeTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00afff")).Bold(true).Padding(0, 1).Underline(true)
+	unreadStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5f5f"))
+
+	// 合并时间线中各直播间的标签颜色, 按标签页下标循环使用
+	roomTagStyles = []lipgloss.Style{
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#00afff")),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#ff8700")),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#87d75f")),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#d75fd7")),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#ffd700")),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#5fd7d7")),
+	}
 
 	medalStyle      = lipgloss.NewStyle().Background(lipgloss.Color("#3FB4F6")).Foreground(lipgloss.Color("#000000"))
 	medalLevelStyle = lipgloss.NewStyle().Background(lipgloss.Color("#3FB4F6")).Foreground(lipgloss.Color("#000000")).Bold(true)
@@ -52,29 +66,53 @@ var (
 	modelIndexes = []string{"danmaku", "sc", "gift", "rank"}
 )
 
+// popularityHistory 人气值趋势保留的采样数, 心跳每 30 秒一次, 约 10 分钟
+const popularityHistory = 20
+
 type (
 	errMsg error
 	App    struct {
+		// 直播间标签页
+		rooms  []*roomState
+		active int
+
+		// 合并时间线, 开启时弹幕框按时间顺序显示所有直播间的弹幕、醒目留言与礼物,
+		// 其余面板与发送仍使用最近选中的直播间
+		timeline *view.Timeline
+		merged   bool
+
 		// 房间信息
 		roomInfoBox viewport.Model
-		roomInfo    bilibili.RoomInfo
 
 		// sc 醒目留言
-		sc    *ds.RingBuffer[string]
 		scBox viewport.Model
 
 		// 弹幕
-		messages    *ds.RingBuffer[string]
 		messageBox  viewport.Model
 		senderStyle lipgloss.Style
 
 		// 礼物
-		gifts   *ds.RingBuffer[string]
 		giftBox viewport.Model
 
 		// 打榜
 		rankBox viewport.Model
 
+		// 换行缓存与等待重绘的视图
+		messageCache view.WrapCache
+		scCache      view.WrapCache
+		giftCache    view.WrapCache
+		dirty        dirty
+		flushPending bool
+
+		// 搜索结果, 不为 nil 时弹幕框显示搜索结果
+		searchResults []string
+
+		// 表情选择器, 打开时显示在弹幕框的位置
+		picker *emotePicker
+
+		// 调试面板开启时, 打榜列表替换为未解析命令的统计
+		showRawCmds bool
+
 		// 进房
 		interInfo viewport.Model
 
@@ -92,20 +130,7 @@ type (
 	}
 )
 
-func NewApp(cookie string, roomID int64) *App {
-	var err error
-	cookie = cmp.Or(cookie, config.Config.Cookie)
-	roomID = cmp.Or(roomID, config.Config.RoomID)
-
-	cli, err = bilibili.NewClient(cookie, uint32(roomID))
-	if err != nil {
-		panic(err)
-	}
-
-	if err := cli.Start(context.Background()); err != nil {
-		panic(err)
-	}
-
+func NewApp(rooms ...client.Room) *App {
 	roomInfo := viewport.New(30, 1)
 	roomInfo.KeyMap = viewport.KeyMap{}
 
@@ -126,7 +151,7 @@ func NewApp(cookie string, roomID int64) *App {
 	giftBox.Style = rankBox.Style.Border(normalBorderStyle)
 
 	inputArea := textarea.New()
-	inputArea.Placeholder = "say something..."
+	inputArea.Placeholder = inputPlaceholder
 	inputArea.Focus()
 	inputArea.Prompt = "┃ "
 	inputArea.CharLimit = 280
@@ -142,12 +167,9 @@ func NewApp(cookie string, roomID int64) *App {
 
 	app := &App{
 		roomInfoBox: roomInfo,
-		messages:    ds.NewRingBufferWithSize[string](config.Config.History.Danmaku),
 		messageBox:  messageBox,
-		sc:          ds.NewRingBufferWithSize[string](config.Config.History.SC),
 		scBox:       scBox,
 		rankBox:     rankBox,
-		gifts:       ds.NewRingBufferWithSize[string](config.Config.History.Gift),
 		giftBox:     giftBox,
 		interInfo:   interInfo,
 		inputArea:   inputArea,
@@ -155,25 +177,142 @@ func NewApp(cookie string, roomID int64) *App {
 		timeStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color("#545c7e")),
 		err:         nil,
 		mode:        ModeInput,
+		timeline:    view.NewTimeline(config.Config.History.Danmaku + config.Config.History.SC + config.Config.History.Gift),
+	}
+	for _, r := range rooms {
+		app.rooms = append(app.rooms, newRoomState(r))
 	}
 
 	return app
 }
 
 func (m *App) Init() tea.Cmd {
-	return tea.Batch(textarea.Blink, listenMessage)
+	cmds := []tea.Cmd{textarea.Blink}
+	for i, r := range m.rooms {
+		cmds = append(cmds, listenRoom(i, r.Client), fetchDanmakuConfig(i, r.Client))
+	}
+	return tea.Batch(cmds...)
+}
+
+// room 当前标签页
+func (m *App) room() *roomState {
+	return m.rooms[m.active]
+}
+
+// tabCount 标签页数量, 多个直播间时最后一页为合并时间线
+func (m *App) tabCount() int {
+	if len(m.rooms) > 1 {
+		return len(m.rooms) + 1
+	}
+	return len(m.rooms)
+}
+
+// tabIndex 当前标签页下标
+func (m *App) tabIndex() int {
+	if m.merged {
+		return len(m.rooms)
+	}
+	return m.active
+}
+
+// switchRoom 切换到指定标签页并重新渲染所有视图
+func (m *App) switchRoom(idx int) {
+	if m.picker != nil {
+		m.endEmotePicker("")
+	}
+	if idx < 0 || idx >= m.tabCount() {
+		return
+	}
+	m.merged = idx == len(m.rooms)
+	if !m.merged {
+		m.active = idx
+		m.room().unread = 0
+		m.updatePrompt()
+	}
+	m.refreshAll()
+}
+
+// touch 标记收到新内容的视图等待重绘, 不可见的直播间记为未读
+func (m *App) touch(idx int, d dirty) {
+	if idx == m.active {
+		m.markDirty(d)
+	}
+	switch {
+	case m.merged:
+		m.markDirty(dirtyMessages)
+	case idx != m.active:
+		m.rooms[idx].unread++
+	}
+}
+
+// roomTag 合并时间线中的直播间标签, 过长的名称截断为前 4 个字符
+func (m *App) roomTag(idx int) string {
+	label := []rune(m.rooms[idx].label())
+	if len(label) > 4 {
+		label = label[:4]
+	}
+	return roomTagStyles[idx%len(roomTagStyles)].Render(fmt.Sprintf("[%d %s]", idx+1, string(label)))
+}
+
+func (m *App) refreshAll() {
+	m.refreshRoomInfo()
+	m.refreshMessages()
+	m.refreshSC()
+	m.refreshGifts()
+	m.refreshRank()
+	m.interInfo.SetContent(m.room().interact)
+}
+
+// tabsView 渲染标签栏, 只有一个直播间时不显示
+func (m *App) tabsView() string {
+	if len(m.rooms) <= 1 {
+		return ""
+	}
+	tabs := make([]string, m.tabCount())
+	for i := range tabs {
+		label := fmt.Sprintf("%d 全部", i+1)
+		if i < len(m.rooms) {
+			r := m.rooms[i]
+			label = fmt.Sprintf("%d %s", i+1, r.label())
+			if r.unread > 0 {
+				label += " " + unreadStyle.Render(fmt.Sprintf("(%d)", r.unread))
+			}
+		}
+		if i == m.tabIndex() {
+			tabs[i] = activeTabStyle.Render(label)
+		} else {
+			tabs[i] = tabStyle.Render(label)
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, tabs...)
 }
 
 func (m *App) refreshRoomInfo() {
+	r := m.room()
+
+	var state string
+	if r.popularity.Len() > 0 {
+		state += fmt.Sprintf(" | %s %d %s",
+			roomInfoPopularityStyle.Render("人气"), r.info.Popularity,
+			roomInfoPopularityStyle.Render(Sparkline(r.popularity.Values())),
+		)
+	}
+	if r.dropped > 0 {
+		state += " | " + connStateStyle.Render(fmt.Sprintf("积压丢弃 %d", r.dropped))
+	}
+	if r.connState != "" {
+		state += " | " + connStateStyle.Render(r.connState)
+	}
 	m.roomInfoBox.SetContent(
-		fmt.Sprintf("%s %s %s | %s %s | %s %s | %s %s | %s %v",
-			roomInfoHomeStyle.Render("  ")+m.roomInfo.Title,
-			roomInfoZoneStyle.Render("["+m.roomInfo.ParentAreaName+" "+m.roomInfo.AreaName+"]"),
-			m.roomInfo.Uname,
-			roomInfoWatchedStyle.Render(" "), m.roomInfo.Watched,
-			roomInfoOnlineStyle.Render(" "), m.roomInfo.Liked,
-			roomInfoOnlineStyle.Render(""), m.roomInfo.Online,
-			roomInfoUptimeStyle.Render(" "), FormatDurationZH(m.roomInfo.Uptime/time.Minute*time.Minute),
+		fmt.Sprintf("%s %s %s | %s %s | %s %s | %s %s | %s %v%s",
+			roomInfoHomeStyle.Render("  ")+r.info.Title,
+			roomInfoZoneStyle.Render("["+r.info.ParentAreaName+" "+r.info.AreaName+"]"),
+			r.info.Uname,
+			roomInfoWatchedStyle.Render(" "), r.info.Watched,
+			roomInfoOnlineStyle.Render(" "), r.info.Liked,
+			roomInfoOnlineStyle.Render(""), r.info.Online,
+			roomInfoUptimeStyle.Render(" "), FormatDurationZH(r.info.Uptime/time.Minute*time.Minute),
+			state,
 		),
 	)
 }
@@ -217,7 +356,7 @@ func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.interInfo.Width = msg.Width
 
 		m.messageBox.Width = (msg.Width - 2*rightWidth)
-		m.messageBox.Height = msg.Height - m.inputArea.Height() - m.roomInfoBox.Height - m.interInfo.Height
+		m.messageBox.Height = msg.Height - m.inputArea.Height() - m.roomInfoBox.Height - m.interInfo.Height - lipgloss.Height(m.tabsView())
 
 		m.scBox.Width = rightWidth
 		m.scBox.Height = topHeight
@@ -228,24 +367,35 @@ func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.rankBox.Width = rightWidth
 		m.rankBox.Height = m.messageBox.Height
 
-		if m.messages.Len() > 0 {
-			// Wrap content before setting it.
-			m.messageBox.SetContent(lipgloss.NewStyle().Width(m.messageBox.Width).Render(strings.Join(m.messages.Values(), "\n")))
-			m.scBox.SetContent(lipgloss.NewStyle().Width(m.scBox.Width).Render(strings.Join(m.sc.Values(), "\n")))
-		}
-		if m.mode == ModeInput {
-			m.messageBox.GotoBottom()
-			m.scBox.GotoBottom()
-		}
+		// Wrap content before setting it.
+		m.refreshAll()
 	case tea.KeyMsg:
 		if subCmd := m.handleKeyMap(msg); subCmd != nil {
 			return m, subCmd
 		}
-	case client.Message:
-		if subcmds := m.handleMessage(msg); len(subcmds) > 0 {
+		if m.mode == ModeEmote && m.inputArea.Value() != m.picker.query {
+			m.picker.filter(m.inputArea.Value())
+		}
+	case roomMsg:
+		if subcmds := m.handleBatch(msg.idx, msg.msgs); len(subcmds) > 0 {
 			cmds = append(cmds, subcmds...)
 		}
-		cmds = append(cmds, listenMessage)
+		if !msg.closed {
+			cmds = append(cmds, listenRoom(msg.idx, m.rooms[msg.idx].Client))
+		}
+		if cmd := m.scheduleFlush(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case flushMsg:
+		m.flush()
+	case danmakuConfigMsg:
+		if msg.err == nil {
+			m.rooms[msg.idx].dmConfig = msg.cfg
+		}
+	case searchResultMsg:
+		m.showSearch(msg)
+	case emoticonsMsg:
+		m.handleEmoticons(msg)
 	case errMsg:
 		m.err = msg
 		return m, nil
@@ -255,20 +405,30 @@ func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *App) View() string {
+	messages := m.messageBox.View()
+	if m.picker != nil {
+		messages = m.emotePickerView()
+	}
 	center := lipgloss.JoinHorizontal(
 		lipgloss.Top,
-		m.messageBox.View(),
+		messages,
 		lipgloss.JoinVertical(lipgloss.Top, m.scBox.View(), m.giftBox.View()),
 		m.rankBox.View(),
 	)
 
+	views := []string{m.roomInfoBox.View()}
+	if tabs := m.tabsView(); tabs != "" {
+		views = append(views, tabs)
+	}
+
 	// 底部是输入框
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
-		m.roomInfoBox.View(),
-		center,
-		m.interInfo.View(),
-		m.inputArea.View(),
+		append(views,
+			center,
+			m.interInfo.View(),
+			m.inputArea.View(),
+		)...,
 	)
 }
 
@@ -278,9 +438,24 @@ func (m *App) handleKeyMap(msg tea.KeyMsg) tea.Cmd {
 		return tea.Quit
 
 	case tea.KeyEsc:
-		if m.mode == ModeInput {
+		switch m.mode {
+		case ModeInput:
 			m.inputArea.Blur()
 			m.mode = ModeNormal
+		case ModeSearch:
+			m.endSearchInput()
+		case ModeEmote:
+			m.endEmotePicker("")
+		case ModeNormal:
+			if m.searchResults != nil {
+				m.searchResults = nil
+				m.refreshMessages()
+			}
+		}
+
+	case tea.KeyRunes:
+		if m.mode == ModeNormal && msg.String() == "/" {
+			m.startSearch()
 		}
 
 	case tea.KeyCtrlI:
@@ -327,7 +502,14 @@ func (m *App) handleKeyMap(msg tea.KeyMsg) tea.Cmd {
 			}
 		}
 
-		if m.mode == ModeInput {
+		switch m.mode {
+		case ModeInput:
+			m.inputArea.Blur()
+			m.mode = ModeNormal
+		case ModeSearch:
+			m.endSearchInput()
+		case ModeEmote:
+			m.endEmotePicker("")
 			m.inputArea.Blur()
 			m.mode = ModeNormal
 		}
@@ -347,114 +529,358 @@ func (m *App) handleKeyMap(msg tea.KeyMsg) tea.Cmd {
 			m.rankBox.KeyMap = defaultKeyMap
 		}
 
+	case tea.KeyCtrlD:
+		m.showRawCmds = !m.showRawCmds
+		m.refreshRank()
+
+	case tea.KeyCtrlO:
+		m.cycleColor()
+
+	case tea.KeyCtrlY:
+		m.cycleMode()
+
+	case tea.KeyCtrlE:
+		if m.mode == ModeInput && !m.merged {
+			return m.startEmotePicker()
+		}
+
+	case tea.KeyUp, tea.KeyDown:
+		if m.mode == ModeEmote {
+			if msg.Type == tea.KeyUp {
+				m.picker.move(-1)
+			} else {
+				m.picker.move(1)
+			}
+		}
+
+	case tea.KeyCtrlN:
+		m.switchRoom((m.tabIndex() + 1) % m.tabCount())
+
+	case tea.KeyCtrlP:
+		m.switchRoom((m.tabIndex() - 1 + m.tabCount()) % m.tabCount())
+
 	case tea.KeyEnter:
 		switch m.mode {
 		case ModeInput:
 			message := m.inputArea.Value()
 			if len(message) > 0 {
-				if err := cli.Send(message); err != nil {
-					m.messages.Push(m.senderStyle.Render("system: ") + "消息发送失败")
-					m.messageBox.SetContent(lipgloss.NewStyle().Width(m.messageBox.Width).Render(strings.Join(m.messages.Values(), "\n")))
-					m.messageBox.GotoBottom()
+				content, opts, err := m.parseSendInput(message)
+				var id uint64
+				if err == nil {
+					id, err = m.room().Client.Send(content, opts)
+				}
+				if err != nil {
+					m.room().messages.Push(m.senderStyle.Render("system: ") + "消息发送失败: " + err.Error())
+				} else {
+					m.room().addEcho(id, content)
 				}
+				m.refreshMessages()
+				m.messageBox.GotoBottom()
 				m.inputArea.Reset()
 			}
+		case ModeEmote:
+			m.chooseEmote()
+		case ModeSearch:
+			query := m.inputArea.Value()
+			m.endSearchInput()
+			if len(query) > 0 {
+				return m.search(query)
+			}
 		}
 	}
 	return nil
 }
 
-func (m *App) handleMessage(msg client.Message) (cmds []tea.Cmd) {
+// handleBatch 处理一批消息, 弹幕数超过积压上限时只显示最新的部分, 其余汇总为一行提示.
+// 醒目留言、礼物等其他消息不受影响, 聊天记录与转发也不受影响
+func (m *App) handleBatch(idx int, msgs []client.Message) (cmds []tea.Cmd) {
+	var danmaku int
+	for _, msg := range msgs {
+		if msg.Type == client.BiliBiliDanmaku {
+			danmaku++
+		}
+	}
+	skip := danmaku - config.Config.Render.Backlog
+	skipped := skip
+
+	for _, msg := range msgs {
+		if skip > 0 && msg.Type == client.BiliBiliDanmaku {
+			skip--
+			if skip == 0 {
+				// 提示放在被省略的最后一条弹幕的位置
+				t := time.Now()
+				if v, ok := msg.Data.(*bilibili.Danmaku); ok {
+					t = v.T
+				}
+				line := m.senderStyle.Render("system: ") + fmt.Sprintf("弹幕过多, 省略 %d 条", skipped)
+				m.rooms[idx].messages.Push(line)
+				m.timeline.Insert(idx, t, line)
+				m.touch(idx, dirtyMessages)
+			}
+			continue
+		}
+		cmds = append(cmds, m.handleMessage(idx, msg)...)
+	}
+	return
+}
+
+func (m *App) handleMessage(idx int, msg client.Message) (cmds []tea.Cmd) {
+	var (
+		r      = m.rooms[idx]
+		active = idx == m.active
+	)
+
 	switch msg.Type {
 	case client.BiliBiliDanmaku:
 		v, ok := msg.Data.(*bilibili.Danmaku)
 		if ok {
 			if !config.Config.Emote.Disable {
-			v.Content = bilibili.ReplaceEmoteCodes(v.Content)
+				v.Content = r.emotes.Replace(v.Content)
+			}
+			var medal string
+			if v.Medal != nil {
+				medal = medalStyle.Render(v.Medal.Name+" ") + medalLevelStyle.Render(fmt.Sprintf("%2d", v.Medal.Level)) + " "
+			}
+			if v.UID != 0 {
+				if len(r.users) >= maxKnownUsers {
+					clear(r.users)
+				}
+				r.users[v.Author] = v.UID
+			}
+			author := SanitizeViewportText(v.Author)
+			content := SanitizeViewportText(v.Content)
+			if v.Emoticon != "" {
+				content = stickerStyle.Render("[" + strings.Trim(content, "[]") + "]")
+			}
+			line := fmt.Sprintf("%s %s%s %s",
+				m.timeStyle.Render(v.T.Format("[15:04]")),
+				medal,
+				m.senderStyle.Render(author+":"),
+				content,
+			)
+			r.messages.Push(line)
+			m.timeline.Insert(idx, v.T, line)
+			m.touch(idx, dirtyMessages)
+		}
+	case client.BiliBiliSuperChat:
+		v, ok := msg.Data.(*bilibili.SuperChat)
+		if ok {
+			if !config.Config.Emote.Disable {
+				v.Content = r.emotes.Replace(v.Content)
+			}
+			line := fmt.Sprintf("%s %s", m.senderStyle.Render(fmt.Sprintf("%s [¥ %d]:", v.Author, v.Price)), v.Content)
+			r.sc.Push(line)
+			m.timeline.Insert(idx, v.T, m.timeStyle.Render(v.T.Format("[15:04]"))+" "+line)
+			m.touch(idx, dirtySC)
+		}
+	case client.BiliBiliGift:
+		v, ok := msg.Data.(*bilibili.Gift)
+		if ok {
+			m.pushGift(idx, v.T, fmt.Sprintf("%s %s %d * %s", m.senderStyle.Render(v.Author), v.Action, v.Num, v.GiftName))
+		}
+	case client.BiliBiliGuardPurchase:
+		v, ok := msg.Data.(*bilibili.GuardPurchase)
+		if ok {
+			m.pushGift(idx, v.T, fmt.Sprintf("%s %d * %s", m.senderStyle.Render(v.Author), v.Num, v.GiftName))
 		}
+	case client.BiliBiliUserEnter:
+		v, ok := msg.Data.(*bilibili.UserEnter)
+		if ok {
+			action := "进入直播间"
 			switch v.Type {
-			case "GUARD_BUY", "COMBO_SEND", "SEND_GIFT":
-				m.gifts.Push(fmt.Sprintf("%s %s", m.senderStyle.Render(v.Author), v.Content))
-				m.giftBox.SetContent(lipgloss.NewStyle().Width(m.giftBox.Width).Render(strings.Join(m.gifts.Values(), "\n")))
-				if m.mode == ModeInput {
-					m.giftBox.GotoBottom()
-				}
-			case "INTERACT_WORD":
-				m.interInfo.SetContent(fmt.Sprintf("%s %s", m.senderStyle.Render(v.Author), v.Content))
-			case "INTERACT_WORD_V2":
-				m.interInfo.SetContent(fmt.Sprintf("%s %s", m.senderStyle.Render(v.Author), v.Content))
-			case "SUPER_CHAT_MESSAGE", "SUPER_CHAT_MESSAGE_JPN":
-				m.sc.Push(fmt.Sprintf("%s %s", m.senderStyle.Render(v.Author+":"), v.Content))
-				m.scBox.SetContent(lipgloss.NewStyle().Width(m.messageBox.Width).Render(strings.Join(m.sc.Values(), "\n")))
-				if m.mode == ModeInput {
-					m.scBox.GotoBottom()
-				}
-			case "WATCHED_CHANGE":
-				m.roomInfo.Watched = v.Content
-				m.refreshRoomInfo()
-			case "ONLINE_RANK_COUNT":
-				m.roomInfo.Online = v.Content
-				m.refreshRoomInfo()
-			case "LIKE_INFO_V3_UPDATE":
-				m.roomInfo.Liked = v.Content
+			case bilibili.InteractFollow:
+				action = "关注了直播间"
+			case bilibili.InteractShare:
+				action = "分享了直播间"
+			}
+			r.interact = fmt.Sprintf("%s %s", m.senderStyle.Render(v.Author), action)
+			if active {
+				m.interInfo.SetContent(r.interact)
+			}
+		}
+	case client.BiliBiliStatsUpdate:
+		v, ok := msg.Data.(*bilibili.StatsUpdate)
+		if ok {
+			switch v.Kind {
+			case bilibili.StatsWatched:
+				r.info.Watched = v.Text
+			case bilibili.StatsOnline:
+				r.info.Online = v.Text
+			case bilibili.StatsLiked:
+				r.info.Liked = v.Text
+			}
+			if active {
 				m.refreshRoomInfo()
-			default:
-				var medal string
-				if v.Medal != nil {
-					medal = medalStyle.Render(v.Medal.Name+" ") + medalLevelStyle.Render(fmt.Sprintf("%2d", v.Medal.Level)) + " "
-				}
-				author := SanitizeViewportText(v.Author)
-				content := SanitizeViewportText(v.Content)
-				m.messages.Push(fmt.Sprintf("%s %s%s %s",
-					m.timeStyle.Render(v.T.Format("[15:04]")),
-					medal,
-					m.senderStyle.Render(author+":"),
-					content,
-				))
-				m.messageBox.SetContent(lipgloss.NewStyle().Width(m.messageBox.Width).Render(strings.Join(m.messages.Values(), "\n")))
-				if m.mode == ModeInput {
-					m.messageBox.GotoBottom()
-				}
 			}
 		}
 	case client.BiliBiliRankInfo:
 		v, ok := msg.Data.([]*bilibili.OnlineRankUser)
 		if ok {
-
-			users := make([]string, len(v))
-			for i, u := range v {
-				var (
-					t     = "  "
-					score = strconv.Itoa(int(u.Score))
-				)
-				if int(u.Rank) <= len(rankIcons) {
-					t = rankStyle[u.Rank-1].Render(rankIcons[u.Rank-1])
-				}
-				info := fmt.Sprintf("%s %s", t, u.Name)
-
-				spaceLen := m.rankBox.Width - lipgloss.Width(info) - lipgloss.Width(score) - m.rankBox.Style.GetHorizontalBorderSize()
-				users[i] = info + strings.Repeat(" ", spaceLen) + score
-
+			r.rank = v
+			if active {
+				m.refreshRank()
+			}
+		}
+	case client.BiliBiliRaw:
+		v, ok := msg.Data.(*bilibili.RawEvent)
+		if ok {
+			if _, seen := r.rawCmds[v.Cmd]; !seen {
+				r.rawCmdNames = append(r.rawCmdNames, v.Cmd)
+			}
+			r.rawCmds[v.Cmd]++
+			if active && m.showRawCmds {
+				m.refreshRank()
 			}
-			m.rankBox.SetContent(strings.Join(users, "\n"))
 		}
 	case client.BiliBiliRoomInfo:
 		v, ok := msg.Data.(*bilibili.RoomInfo)
 		if ok {
-			m.roomInfo.Title = v.Title
-			m.roomInfo.Uname = v.Uname
-			m.roomInfo.ParentAreaName = v.ParentAreaName
-			m.roomInfo.AreaName = v.AreaName
-			m.roomInfo.Uptime = v.Uptime
-			m.refreshRoomInfo()
+			r.info.Title = v.Title
+			r.info.Uname = v.Uname
+			r.info.ParentAreaName = v.ParentAreaName
+			r.info.AreaName = v.AreaName
+			r.info.Uptime = v.Uptime
+			if active {
+				m.refreshRoomInfo()
+			}
+		}
+	case client.BiliBiliPopularity:
+		v, ok := msg.Data.(*bilibili.Popularity)
+		if ok {
+			r.info.Popularity = v.Value
+			r.popularity.Push(v.Value)
+			if active {
+				m.refreshRoomInfo()
+			}
+		}
+	case client.BiliBiliDropped:
+		v, ok := msg.Data.(*bilibili.DropStats)
+		if ok {
+			r.dropped = v.Total
+			if active {
+				m.refreshRoomInfo()
+			}
+		}
+	case client.BiliBiliSendResult:
+		v, ok := msg.Data.(*bilibili.SendResult)
+		if ok {
+			r.handleSendResult(v)
+			if !v.OK() {
+				line := "消息发送失败: " + v.Error()
+				if v.Parts > 1 {
+					line = fmt.Sprintf("消息第 %d/%d 部分发送失败: %s", v.Part, v.Parts, v.Error())
+				}
+				r.messages.Push(m.senderStyle.Render("system: ") + line)
+				m.touch(idx, dirtyMessages)
+			} else if active {
+				m.markDirty(dirtyMessages)
+			}
+		}
+	case client.BiliBiliSendEcho:
+		v, ok := msg.Data.(*bilibili.SendEcho)
+		if ok {
+			if warning := r.handleSendEcho(v); warning != "" {
+				r.messages.Push(m.senderStyle.Render("system: ") + warning)
+				m.touch(idx, dirtyMessages)
+			} else if active {
+				m.markDirty(dirtyMessages)
+			}
+		}
+	case client.BiliBiliConnState:
+		v, ok := msg.Data.(*bilibili.ConnState)
+		if ok {
+			switch v.State {
+			case bilibili.ConnReconnecting:
+				r.connState = fmt.Sprintf("连接断开, %s后第%d次重连", FormatDurationZH(v.Delay), v.Attempt)
+			case bilibili.ConnReconnected:
+				r.connState = ""
+				r.messages.Push(m.senderStyle.Render("system: ") + "连接已恢复")
+			}
+			if active {
+				m.refreshRoomInfo()
+				m.markDirty(dirtyMessages)
+			}
 		}
 	}
 	return
 }
 
-func listenMessage() tea.Msg {
-	if msg, ok := <-cli.Receive(); ok {
-		return msg
+// refreshMessages 重新渲染当前标签页的弹幕, 合并模式下渲染合并时间线
+func (m *App) refreshMessages() {
+	lines := m.room().messages.Values()
+	switch {
+	case m.searchResults == nil && !m.merged:
+		lines = append(lines, m.echoLines(m.room())...)
+	case m.searchResults != nil:
+		lines = m.searchResults
+	case m.merged:
+		entries := m.timeline.Entries()
+		lines = make([]string, len(entries))
+		for i, e := range entries {
+			lines[i] = m.roomTag(e.Idx) + " " + e.Line
+		}
 	}
-	return nil
+	m.messageBox.SetContent(m.messageCache.Render(lines, m.messageBox.Width))
+	if m.mode == ModeInput {
+		m.messageBox.GotoBottom()
+	}
+}
+
+// refreshSC 重新渲染当前标签页的醒目留言
+func (m *App) refreshSC() {
+	m.scBox.SetContent(m.scCache.Render(m.room().sc.Values(), m.scBox.Width))
+	if m.mode == ModeInput {
+		m.scBox.GotoBottom()
+	}
+}
+
+// refreshGifts 重新渲染当前标签页的礼物
+func (m *App) refreshGifts() {
+	m.giftBox.SetContent(m.giftCache.Render(m.room().gifts.Values(), m.giftBox.Width))
+	if m.mode == ModeInput {
+		m.giftBox.GotoBottom()
+	}
+}
+
+// refreshRank 渲染打榜列表, 调试模式下改为渲染未解析命令的统计
+func (m *App) refreshRank() {
+	r := m.room()
+
+	if m.showRawCmds {
+		names := slices.Clone(r.rawCmdNames)
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Compare(r.rawCmds[b], r.rawCmds[a])
+		})
+
+		lines := make([]string, len(names))
+		for i, name := range names {
+			count := strconv.Itoa(r.rawCmds[name])
+			spaceLen := m.rankBox.Width - lipgloss.Width(name) - lipgloss.Width(count) - m.rankBox.Style.GetHorizontalBorderSize()
+			lines[i] = name + strings.Repeat(" ", max(1, spaceLen)) + count
+		}
+		m.rankBox.SetContent(strings.Join(lines, "\n"))
+		return
+	}
+
+	users := make([]string, len(r.rank))
+	for i, u := range r.rank {
+		var (
+			t     = "  "
+			score = strconv.Itoa(int(u.Score))
+		)
+		if int(u.Rank) <= len(rankIcons) {
+			t = rankStyle[u.Rank-1].Render(rankIcons[u.Rank-1])
+		}
+		info := fmt.Sprintf("%s %s", t, u.Name)
+
+		spaceLen := m.rankBox.Width - lipgloss.Width(info) - lipgloss.Width(score) - m.rankBox.Style.GetHorizontalBorderSize()
+		users[i] = info + strings.Repeat(" ", max(0, spaceLen)) + score
+	}
+	m.rankBox.SetContent(strings.Join(users, "\n"))
+}
+
+func (m *App) pushGift(idx int, t time.Time, line string) {
+	m.rooms[idx].gifts.Push(line)
+	m.timeline.Insert(idx, t, m.timeStyle.Render(t.Format("[15:04]"))+" "+line)
+	m.touch(idx, dirtyGifts)
 }
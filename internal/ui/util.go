@@ -55,3 +55,28 @@ func SanitizeViewportText(s string) string {
 		return r
 	}, s)
 }
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline 将数值序列渲染为迷你走势图
+func Sparkline(values []int64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+
+	runes := make([]rune, len(values))
+	for i, v := range values {
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) * int64(len(sparkBlocks)-1) / (hi - lo))
+		}
+		runes[i] = sparkBlocks[idx]
+	}
+	return string(runes)
+}
@@ -0,0 +1,46 @@
+// Package view 界面中与终端和配置无关的视图数据, 单独成包以便测试.
+package view
+
+import (
+	"slices"
+	"time"
+)
+
+// Entry 合并时间线中的一行
+type Entry struct {
+	T    time.Time
+	Idx  int // 来源标签页下标
+	Line string
+}
+
+// Timeline 按时间排序的多直播间合并记录, 超出 limit 时丢弃最早的记录
+type Timeline struct {
+	entries []Entry
+	limit   int
+}
+
+func NewTimeline(limit int) *Timeline {
+	return &Timeline{limit: max(1, limit)}
+}
+
+// Insert 按时间顺序插入, 同一时间的记录保持到达顺序
+func (t *Timeline) Insert(idx int, at time.Time, line string) {
+	i, _ := slices.BinarySearchFunc(t.entries, at, func(e Entry, at time.Time) int {
+		if e.T.After(at) {
+			return 1
+		}
+		return -1
+	})
+	if len(t.entries) >= t.limit && i == 0 {
+		// 比所有记录都早, 插入后也会被立即丢弃
+		return
+	}
+	t.entries = slices.Insert(t.entries, i, Entry{T: at, Idx: idx, Line: line})
+	if over := len(t.entries) - t.limit; over > 0 {
+		t.entries = slices.Delete(t.entries, 0, over)
+	}
+}
+
+func (t *Timeline) Entries() []Entry {
+	return t.entries
+}
@@ -0,0 +1,55 @@
+package view
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	base := time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+	tests := []struct {
+		name   string
+		limit  int
+		insert []Entry
+		want   []string
+	}{
+		{
+			name:  "ordered by time across rooms",
+			limit: 10,
+			// 各直播间的消息按到达顺序插入, 时间线按消息时间排序
+			insert: []Entry{{at(3), 0, "a3"}, {at(1), 1, "b1"}, {at(5), 0, "a5"}, {at(2), 1, "b2"}, {at(4), 2, "c4"}},
+			want:   []string{"b1", "b2", "a3", "c4", "a5"},
+		},
+		{
+			name:   "same time keeps arrival order",
+			limit:  10,
+			insert: []Entry{{at(1), 0, "a"}, {at(1), 1, "b"}, {at(0), 2, "c"}, {at(1), 0, "d"}},
+			want:   []string{"c", "a", "b", "d"},
+		},
+		{
+			name:  "drops oldest over limit",
+			limit: 3,
+			// 超出上限时丢弃最早的记录, 早于所有记录的消息不再插入
+			insert: []Entry{{at(2), 0, "2"}, {at(4), 0, "4"}, {at(3), 1, "3"}, {at(5), 1, "5"}, {at(1), 0, "1"}},
+			want:   []string{"3", "4", "5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := NewTimeline(tt.limit)
+			for _, e := range tt.insert {
+				tl.Insert(e.Idx, e.T, e.Line)
+			}
+			var got []string
+			for _, e := range tl.Entries() {
+				got = append(got, e.Line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
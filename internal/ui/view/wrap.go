@@ -0,0 +1,43 @@
+package view
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WrapCache 缓存每行按宽度换行后的结果, 只对新出现的行换行, 宽度变化时全部重新换行
+type WrapCache struct {
+	width int
+	lines map[string]string
+}
+
+// Render 换行并拼接所有行, 结果与对拼接后的整体换行一致
+func (c *WrapCache) Render(lines []string, width int) string {
+	if c.lines == nil || c.width != width {
+		c.width = width
+		c.lines = make(map[string]string, len(lines))
+	}
+	// 已不在视图中的行过多时清理, 避免缓存无限增长
+	if len(c.lines) > 2*len(lines)+64 {
+		used := make(map[string]string, len(lines))
+		for _, line := range lines {
+			if v, ok := c.lines[line]; ok {
+				used[line] = v
+			}
+		}
+		c.lines = used
+	}
+
+	style := lipgloss.NewStyle().Width(width)
+	wrapped := make([]string, len(lines))
+	for i, line := range lines {
+		v, ok := c.lines[line]
+		if !ok {
+			v = style.Render(line)
+			c.lines[line] = v
+		}
+		wrapped[i] = v
+	}
+	return strings.Join(wrapped, "\n")
+}
@@ -0,0 +1,51 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestWrapCache(t *testing.T) {
+	lines := []string{"短的一行", "a line that is long enough to be wrapped at a narrow width", "", "晚上好晚上好晚上好晚上好晚上好"}
+	whole := func(lines []string, width int) string {
+		return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+	}
+
+	var c WrapCache
+	for _, width := range []int{20, 20, 12, 40} {
+		// 使用缓存与宽度变化后的结果都与整体换行一致
+		if got, want := c.Render(lines, width), whole(lines, width); got != want {
+			t.Errorf("Render width %d =\n%s\nwant\n%s", width, got, want)
+		}
+		if c.width != width || len(c.lines) != len(lines) {
+			t.Errorf("cache width %d with %d lines, want width %d with %d lines", c.width, len(c.lines), width, len(lines))
+		}
+	}
+
+	// 缓存的结果被直接使用, 不再重新换行
+	c.lines[lines[0]] = "cached"
+	if got := c.Render(lines[:1], 40); got != "cached" {
+		t.Errorf("Render cached line = %q, want cached", got)
+	}
+}
+
+func TestWrapCachePrune(t *testing.T) {
+	var c WrapCache
+	var history []string
+	for i := range 200 {
+		history = append(history, fmt.Sprintf("line %d", i))
+	}
+	c.Render(history, 20)
+
+	// 视图中的行远少于缓存时, 只保留仍在视图中的行
+	visible := history[190:]
+	if got, want := c.Render(visible, 20), lipgloss.NewStyle().Width(20).Render(strings.Join(visible, "\n")); got != want {
+		t.Errorf("Render after prune =\n%s\nwant\n%s", got, want)
+	}
+	if len(c.lines) != len(visible) {
+		t.Errorf("cache has %d lines after prune, want %d", len(c.lines), len(visible))
+	}
+}
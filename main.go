@@ -1,23 +1,225 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BYT0723/bilichat/internal/archive"
+	"github.com/BYT0723/bilichat/internal/client"
+	"github.com/BYT0723/bilichat/internal/client/bilibili"
+	"github.com/BYT0723/bilichat/internal/config"
+	"github.com/BYT0723/bilichat/internal/event"
+	"github.com/BYT0723/bilichat/internal/relay"
 	"github.com/BYT0723/bilichat/internal/ui"
+	"github.com/BYT0723/go-tools/logx"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// int64List 可重复指定的 int64 参数
+type int64List []int64
+
+func (l *int64List) String() string {
+	ss := make([]string, len(*l))
+	for i, v := range *l {
+		ss[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(ss, ",")
+}
+
+func (l *int64List) Set(s string) error {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, v)
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mock-server":
+			runMockServer(os.Args[2:])
+			return
+		case "search":
+			runSearch(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		cookie string
-		roomId int64
+		cookie   string
+		roomIds  int64List
+		record   string
+		replay   string
+		speed    float64
+		headless bool
+		relayTo  string
 	)
 
-	flag.Int64Var(&roomId, "id", 0, "room id")
+	flag.Var(&roomIds, "id", "room id, repeat to watch multiple rooms")
 	flag.StringVar(&cookie, "cookie", "", "user cookie")
+	flag.StringVar(&record, "record", "", "record raw websocket frames to file")
+	flag.StringVar(&replay, "replay", "", "replay a recorded file instead of connecting")
+	flag.Float64Var(&speed, "speed", 1, "replay speed, 0 replays instantly")
+	flag.StringVar(&relayTo, "relay", "", "serve events over SSE/WebSocket and the overlay page on this address")
+	flag.BoolVar(&headless, "headless", false, "print events as JSON lines to stdout instead of starting the UI")
 	flag.Parse()
 
-	if _, err := tea.NewProgram(ui.NewApp(cookie, int64(roomId))).Run(); err != nil {
+	if len(roomIds) == 0 {
+		roomIds = config.Config.Rooms
+	}
+	if len(roomIds) == 0 {
+		roomIds = int64List{config.Config.RoomID}
+	}
+
+	var overrides map[string]string
+	if !config.Config.Emote.Disable {
+		var err error
+		if overrides, err = bilibili.LoadEmoteOverride(config.EmoteOverridePath()); err != nil {
+			logx.Errorf("load emote override, err: %v", err)
+		}
+	}
+
+	var rooms []client.Room
+	if replay != "" {
+		rc := bilibili.NewReplayClient(replay, speed)
+		rc.Emotes().SetOverride(overrides)
+		// 回放时不联网, 使用该直播间缓存的表情包
+		if pkgs, ok := bilibili.LoadEmoteCache(bilibili.EmoteCachePath(config.EmoteCacheDir(), roomIds[0]), 0); ok {
+			rc.Emotes().AddEmoticons(pkgs)
+		}
+		rooms = append(rooms, client.Room{ID: roomIds[0], Client: rc})
+	} else {
+		cookie = cmp.Or(cookie, config.Config.Cookie)
+		for _, id := range roomIds {
+			var recordPath string
+			if record != "" {
+				recordPath = record
+				if len(roomIds) > 1 {
+					// 多个直播间时每个直播间单独录制, 如 rec.bin -> rec-123.bin
+					ext := filepath.Ext(record)
+					recordPath = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(record, ext), id, ext)
+				}
+			}
+
+			cli, closer, err := newClient(cookie, id, recordPath, overrides)
+			if err != nil {
+				panic(err)
+			}
+			if closer != nil {
+				defer closer()
+			}
+			rooms = append(rooms, client.Room{ID: id, Client: cli})
+		}
+	}
+
+	// 退出时停止转发服务与所有客户端
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+
+	// 解码后的消息在交给界面之前分发给转发服务与聊天记录
+	var sinks []func(roomID int64, msg client.Message)
+	if relayTo = cmp.Or(relayTo, config.Config.Relay); relayTo != "" {
+		srv := relay.NewServer(relay.WithAllowedOrigins(config.Config.RelayOrigins...))
+		sinks = append(sinks, func(roomID int64, msg client.Message) {
+			if e, ok := event.FromMessage(roomID, msg); ok {
+				srv.Publish(e)
+			}
+		})
+		go func() {
+			if err := srv.ListenAndServe(ctx, relayTo); err != nil {
+				logx.Errorf("relay server, err: %v", err)
+			}
+		}()
+	}
+	// 回放的内容已经记录过, 不再重复保存
+	if !config.Config.Archive.Disable && replay == "" {
+		a, err := archive.Open(config.ArchiveDir(), time.Duration(config.Config.Archive.RetentionDays)*24*time.Hour)
+		if err != nil {
+			panic(err)
+		}
+		defer a.Close()
+		sinks = append(sinks, a.Record)
+	}
+	if len(sinks) > 0 {
+		for i, r := range rooms {
+			rooms[i].Client = client.Tap(r.Client, func(msg client.Message) {
+				for _, sink := range sinks {
+					sink(r.ID, msg)
+				}
+			})
+		}
+	}
+
+	for _, r := range rooms {
+		if err := r.Client.Start(ctx); err != nil {
+			panic(err)
+		}
+		defer r.Client.Stop()
+	}
+
+	if headless {
+		runHeadless(os.Stdout, rooms)
+		return
+	}
+
+	if _, err := tea.NewProgram(ui.NewApp(rooms...)).Run(); err != nil {
 		panic(err)
 	}
 }
+
+// newClient 按配置创建直播间客户端, recordPath 不为空时录制原始帧, 返回的 closer 用于关闭录制文件
+func newClient(cookie string, roomID int64, recordPath string, emoteOverride map[string]string) (cli client.Client, closer func(), err error) {
+	overflow, err := bilibili.ParseOverflowPolicy(config.Config.Overflow)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []bilibili.Option{
+		bilibili.WithOverflowPolicy(overflow),
+		bilibili.WithMaxLength(config.Config.Send.MaxLength),
+		bilibili.WithProtover(config.Config.Protover),
+		bilibili.WithEmoteCache(config.EmoteCacheDir(), time.Duration(config.Config.Emote.CacheHours)*time.Hour),
+		bilibili.WithEmoteOverride(emoteOverride),
+		bilibili.WithEndpoints(bilibili.Endpoints{
+			API:      config.Config.Endpoints.API,
+			Live:     config.Config.Endpoints.Live,
+			Origin:   config.Config.Endpoints.Origin,
+			Stream:   config.Config.Endpoints.Stream,
+			Insecure: config.Config.Endpoints.Insecure,
+		}),
+	}
+	if v := config.Config.Send.IntervalMS; v != nil {
+		opts = append(opts, bilibili.WithSendInterval(time.Duration(*v)*time.Millisecond))
+	}
+	if v := config.Config.Send.Retries; v != nil {
+		opts = append(opts, bilibili.WithSendRetries(*v))
+	}
+	if recordPath != "" {
+		recorder, err := bilibili.CreateRecorder(recordPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		closer = func() { recorder.Close() }
+		opts = append(opts, bilibili.WithRecorder(recorder))
+	}
+
+	cli, err = bilibili.NewClient(cookie, uint32(roomID), opts...)
+	if err != nil {
+		if closer != nil {
+			closer()
+		}
+		return nil, nil, err
+	}
+	return cli, closer, nil
+}
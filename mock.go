@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/mock"
+)
+
+// runMockServer 启动模拟的 bilibili 直播服务
+func runMockServer(args []string) {
+	var (
+		fs       = flag.NewFlagSet("mock-server", flag.ExitOnError)
+		addr     string
+		interval time.Duration
+		batch    int
+	)
+	fs.StringVar(&addr, "addr", "127.0.0.1:8080", "listen address")
+	fs.DurationVar(&interval, "interval", time.Second, "interval between scripted event batches")
+	fs.IntVar(&batch, "batch", 3, "scripted events per batch")
+	_ = fs.Parse(args)
+
+	srv := mock.NewServer()
+	srv.Interval = interval
+	srv.BatchSize = batch
+
+	fmt.Printf(`mock server listening on %[1]s, add the following to config.yaml:
+
+endpoints:
+  api: http://%[1]s
+  live: http://%[1]s
+  origin: http://%[1]s
+  insecure: true
+`, addr)
+
+	if err := srv.ListenAndServe(addr); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BYT0723/bilichat/internal/archive"
+	"github.com/BYT0723/bilichat/internal/config"
+	"github.com/BYT0723/bilichat/internal/event"
+)
+
+// runSearch 在聊天记录中搜索, 结果以类似 grep 的格式输出, 匹配行以 ':' 分隔, 上下文以 '-' 分隔
+func runSearch(args []string) {
+	var (
+		fs      = flag.NewFlagSet("search", flag.ExitOnError)
+		rooms   int64List
+		q       archive.Query
+		types   string
+		pattern string
+		since   string
+		until   string
+		context int
+		limit   int
+		asJSON  bool
+	)
+	fs.Var(&rooms, "room", "room id, repeat to search multiple rooms")
+	fs.StringVar(&q.User, "user", "", "user name contains")
+	fs.Int64Var(&q.UID, "uid", 0, "user id")
+	fs.StringVar(&q.Keyword, "keyword", "", "content contains")
+	fs.StringVar(&pattern, "regex", "", "content matches regular expression")
+	fs.StringVar(&types, "type", "", "comma separated event types, e.g. danmaku,superchat,gift,guard,enter,follow,share")
+	fs.StringVar(&since, "since", "", "start time, e.g. 2006-01-02, \"2006-01-02 15:04\", 24h or 7d")
+	fs.StringVar(&until, "until", "", "end time, same format as -since")
+	fs.IntVar(&context, "C", 0, "events of context around each match")
+	fs.IntVar(&limit, "limit", 0, "only print the newest N matches, 0 prints all")
+	fs.BoolVar(&asJSON, "json", false, "print matches as JSON lines")
+	_ = fs.Parse(args)
+
+	var (
+		now = time.Now()
+		err error
+	)
+	q.Rooms = rooms
+	if rest := fs.Args(); len(rest) > 0 && q.Keyword == "" {
+		q.Keyword = strings.Join(rest, " ")
+	}
+	if pattern != "" {
+		if q.Regexp, err = regexp.Compile(pattern); err != nil {
+			exitf("invalid -regex: %v", err)
+		}
+	}
+	if types != "" {
+		if q.Types, err = archive.ParseTypes(types); err != nil {
+			exitf("invalid -type: %v", err)
+		}
+	}
+	if since != "" {
+		if q.Since, err = archive.ParseTime(since, now); err != nil {
+			exitf("invalid -since: %v", err)
+		}
+	}
+	if until != "" {
+		if q.Until, err = archive.ParseTime(until, now); err != nil {
+			exitf("invalid -until: %v", err)
+		}
+	}
+
+	results, err := archive.Search(config.ArchiveDir(), q, context, limit)
+	if err != nil {
+		exitf("search: %v", err)
+	}
+	if config.Config.Archive.Disable {
+		fmt.Fprintln(os.Stderr, archiveDisabled)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, r := range results {
+			_ = enc.Encode(r.Event)
+		}
+		return
+	}
+
+	for i, r := range results {
+		if context > 0 && i > 0 {
+			fmt.Println("--")
+		}
+		for _, e := range r.Before {
+			printEvent(&e, '-')
+		}
+		printEvent(&r.Event, ':')
+		for _, e := range r.After {
+			printEvent(&e, '-')
+		}
+	}
+}
+
+func printEvent(e *event.Event, sep byte) {
+	fmt.Printf("%d%c%s%c%s%c%s(%d): %s\n",
+		e.Room, sep,
+		e.Timestamp.Local().Format(time.DateTime), sep,
+		e.Type, sep,
+		e.User, e.UID, e.Summary(),
+	)
+}
+
+// archiveDisabled 关闭聊天记录时搜索与导出的提示
+const archiveDisabled = "archive is disabled (archive.disable in config), new events are not recorded"
+
+func exitf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}